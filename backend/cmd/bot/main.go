@@ -3,13 +3,21 @@ package main
 import (
 	"log"
 	"net/http"
+	"novissima/internal/admin"
+	"novissima/internal/auth"
 	"novissima/internal/config"
 	"novissima/internal/content"
 	"novissima/internal/database"
+	"novissima/internal/deliveries"
+	"novissima/internal/latin"
+	"novissima/internal/liturgical"
 	"novissima/internal/logging"
+	"novissima/internal/messaging"
 	"novissima/internal/scheduler"
+	"novissima/internal/storage"
 	"novissima/internal/twilio"
 	"novissima/internal/users"
+	_ "time/tzdata"
 )
 
 func corsMiddleware(next http.Handler) http.Handler {
@@ -17,7 +25,7 @@ func corsMiddleware(next http.Handler) http.Handler {
 		origin := r.Header.Get("Origin")
 		if origin == "http://localhost:3000" {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
 		}
@@ -38,40 +46,167 @@ func main() {
 		log.Fatalf("Error loading config: %v", err)
 	}
 	
-	db := database.NewSupabaseDB(
-		cfg.SupabaseURL,
-		cfg.SupabaseKey,
-		cfg.SupabaseEmail,
-		cfg.SupabasePassword,
-	)
+	var db database.Database
+	switch cfg.DatabaseBackend {
+	case "postgres":
+		db = database.NewPostgresDB(cfg.DatabaseURL)
+	case "memory":
+		db = database.NewMemoryDB()
+	default:
+		db = database.NewSupabaseDB(
+			cfg.SupabaseURL,
+			cfg.SupabaseKey,
+			cfg.SupabaseEmail,
+			cfg.SupabasePassword,
+		)
+	}
 	if err := db.Connect(); err != nil {
 		log.Fatal(err)
 	}
+	stores := db.Stores()
+	
+	mux := http.NewServeMux()
+	
+	var imageStore content.ImageStore
+	switch cfg.ImageStore {
+	case "local":
+		localStore := storage.NewLocalStore(cfg.LocalImageDir, cfg.LocalImageBaseURL)
+		mux.Handle("/images/", http.StripPrefix("/images", localStore.Handler()))
+		imageStore = localStore
+	case "s3":
+		s3Store, err := storage.NewS3Store(storage.S3Options{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+			UseSSL:          cfg.S3UseSSL,
+			PublicBaseURL:   cfg.S3PublicBaseURL,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		imageStore = s3Store
+	default:
+		supabaseDB, ok := db.(*database.SupabaseDB)
+		if !ok {
+			log.Fatal("IMAGE_STORE=supabase requires DATABASE_BACKEND=supabase")
+		}
+		imageStore = storage.NewSupabaseStore(supabaseDB.Client().Storage, cfg.ContentBucketName)
+	}
+	
+	var calendarRules *liturgical.Rules
+	if cfg.LiturgicalCalendarFile != "" {
+		calendarRules, err = liturgical.LoadRules(cfg.LiturgicalCalendarFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 	
-	loggingService := logging.NewService(db.GetClient())
-	userService := users.NewService(db.GetClient(), loggingService)
+	loggingService := logging.NewService(stores.Logs)
+	userService := users.NewService(stores.Users, loggingService)
 	contentService := content.NewService(
-		db.GetClient(), 
+		stores.Content,
+		stores.Overrides,
+		imageStore,
 		loggingService,
-		cfg.ContentBucketName,
+		content.RotationPolicy{
+			Themes:       cfg.RotationThemes,
+			StartDate:    cfg.RotationStartDate,
+			MinRepeatGap: cfg.RotationMinGap,
+			Calendar:     calendarRules,
+		},
 	)
+	
+	var messenger messaging.Messenger
+	switch cfg.Messenger {
+	case "recorder":
+		messenger = messaging.NewRecorder()
+	default:
+		messenger = twilio.NewClient(
+			cfg.TwilioAccountSid,
+			cfg.TwilioAuthToken,
+			cfg.TwilioPhoneNumber,
+			cfg.TwilioContentSid,
+			cfg.TwilioMessagingServiceSid,
+		)
+	}
+	
+	deliveryService := deliveries.NewService(stores.Deliveries)
+	lexicon, err := latin.LoadLexicon()
+	if err != nil {
+		log.Fatal(err)
+	}
 	twilioService := twilio.NewService(
 		userService,
 		contentService,
-		cfg.TwilioAccountSid,
+		deliveryService,
+		loggingService,
+		messenger,
 		cfg.TwilioAuthToken,
-		cfg.TwilioPhoneNumber,
-		cfg.TwilioContentSid,
-		cfg.TwilioMessagingServiceSid,
+		cfg.PublicBaseURL,
+		cfg.TwilioValidateSignatures,
+		cfg.ReviewerNumbers,
+		lexicon,
+	)
+	schedulerService := scheduler.NewService(
+		contentService,
+		twilioService,
+		userService,
+		deliveryService,
+		loggingService,
+		scheduler.Options{
+			Enabled:            cfg.SchedulerEnabled,
+			Location:           cfg.SchedulerLocation,
+			DailyBroadcastSpec: cfg.DailyBroadcastSchedule,
+			WeeklyDigestSpec:   cfg.WeeklyDigestSchedule,
+		},
 	)
-	schedulerService := scheduler.NewService(contentService, twilioService, loggingService)
 	
-	mux := http.NewServeMux()
+	apiKeys, err := auth.ParseAPIKeys(cfg.AdminAPIKeys)
+	if err != nil {
+		log.Fatalf("Invalid ADMIN_API_KEYS: %v", err)
+	}
+	if cfg.SupabaseJWTSecret == "" && len(apiKeys) == 0 {
+		log.Println("Warning: neither SUPABASE_JWT_SECRET nor ADMIN_API_KEYS is set, the admin API will reject every request")
+	}
+	authService := auth.NewService(cfg.SupabaseJWTSecret, apiKeys, loggingService)
+	adminService := admin.NewService(userService, deliveryService, loggingService)
 	
-	mux.HandleFunc("/content", contentService.HandleCreateContent)
-	mux.HandleFunc("/twilio/webhook", twilioService.HandleWebhook)
+	mux.HandleFunc("GET /content", authService.Require(auth.RoleEditor, contentService.HandleListContent))
+	mux.HandleFunc("POST /content", authService.Require(auth.RoleEditor, contentService.HandleCreateContent))
+	mux.HandleFunc("POST /content/import", authService.Require(auth.RoleEditor, contentService.HandleImportContent))
+	mux.HandleFunc("GET /content/duplicates", authService.Require(auth.RoleEditor, contentService.HandleScanDuplicates))
+	mux.HandleFunc("POST /content/duplicates/backfill", authService.Require(auth.RoleAdmin, contentService.HandleBackfillHashes))
+	mux.HandleFunc("GET /content/export", authService.Require(auth.RoleAdmin, contentService.HandleExportContent))
+	mux.HandleFunc("POST /content/restore", authService.Require(auth.RoleAdmin, contentService.HandleRestoreContent))
+	mux.HandleFunc("GET /content/{id}", authService.Require(auth.RoleEditor, contentService.HandleGetContent))
+	mux.HandleFunc("PUT /content/{id}", authService.Require(auth.RoleEditor, contentService.HandleUpdateContent))
+	mux.HandleFunc("PATCH /content/{id}", authService.Require(auth.RoleEditor, contentService.HandleUpdateContent))
+	mux.HandleFunc("DELETE /content/{id}", authService.Require(auth.RoleAdmin, contentService.HandleDeleteContent))
+	mux.HandleFunc("POST /content/{id}/submit", authService.Require(auth.RoleEditor, contentService.HandleTransition(content.ActionSubmit)))
+	mux.HandleFunc("POST /content/{id}/approve", authService.Require(auth.RoleReviewer, contentService.HandleTransition(content.ActionApprove)))
+	mux.HandleFunc("POST /content/{id}/reject", authService.Require(auth.RoleReviewer, contentService.HandleTransition(content.ActionReject)))
+	mux.HandleFunc("POST /content/{id}/retire", authService.Require(auth.RoleReviewer, contentService.HandleTransition(content.ActionRetire)))
+	mux.HandleFunc("GET /content/{id}/preview", authService.Require(auth.RoleEditor, twilioService.HandlePreview))
+	mux.HandleFunc("POST /content/{id}/send-test", authService.Require(auth.RoleEditor, twilioService.HandleSendTest))
+	mux.HandleFunc("/twilio/webhook", twilioService.RequireSignature(twilioService.HandleWebhook))
+	mux.HandleFunc("/twilio/status", twilioService.RequireSignature(twilioService.HandleStatus))
+	mux.HandleFunc("GET /deliveries/summary", authService.Require(auth.RoleEditor, deliveryService.HandleSummary))
+	mux.HandleFunc("GET /admin/schedule", authService.Require(auth.RoleAdmin, schedulerService.HandleSchedule))
+	mux.HandleFunc("POST /admin/schedule/{job}/run", authService.Require(auth.RoleAdmin, schedulerService.HandleRunJob))
+	mux.HandleFunc("GET /admin/overrides", authService.Require(auth.RoleAdmin, contentService.HandleListOverrides))
+	mux.HandleFunc("POST /admin/overrides", authService.Require(auth.RoleAdmin, contentService.HandleCreateOverride))
+	mux.HandleFunc("DELETE /admin/overrides/{id}", authService.Require(auth.RoleAdmin, contentService.HandleDeleteOverride))
+	mux.HandleFunc("GET /admin/users", authService.Require(auth.RoleAdmin, adminService.HandleListUsers))
+	mux.HandleFunc("GET /admin/users/export", authService.Require(auth.RoleAdmin, adminService.HandleExportUsers))
+	mux.HandleFunc("GET /admin/users/{phone}", authService.Require(auth.RoleAdmin, adminService.HandleGetUser))
+	mux.HandleFunc("PATCH /admin/users/{phone}", authService.Require(auth.RoleAdmin, adminService.HandleUpdateUser))
+	mux.HandleFunc("GET /admin/users/{phone}/history", authService.Require(auth.RoleAdmin, adminService.HandleUserHistory))
 	
-	schedulerService.Start()
+	if err := schedulerService.Start(); err != nil {
+		log.Fatal(err)
+	}
 	
 	log.Println("Server starting on port 8080...")
 	if err := http.ListenAndServe(":8080", corsMiddleware(mux)); err != nil {
@@ -0,0 +1,334 @@
+// Command contentctl manages content through the bot's admin API.
+//
+// Usage:
+//
+//	contentctl import -rows texts.csv [-images images.zip] [-all-or-nothing]
+//	contentctl export [-o backup.zip]
+//	contentctl restore -archive backup.zip
+//
+// The API is read from NOVISSIMA_API_URL (default http://localhost:8080) and
+// the bearer token from NOVISSIMA_API_TOKEN; both can be overridden with
+// -api and -token.
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"novissima/internal/content"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "import":
+		err = runImport(os.Args[2:])
+	case "export":
+		err = runExport(os.Args[2:])
+	case "restore":
+		err = runRestore(os.Args[2:])
+	case "help", "-h", "-help", "--help":
+		usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage: contentctl <command> [flags]
+
+Commands:
+  import   Bulk import content from a CSV or JSON file and a zip of images
+  export   Download the whole content library as a backup archive
+  restore  Load a backup archive, re-uploading its images
+
+Run "contentctl <command> -h" for the flags of a command.`)
+}
+
+// client talks to the admin API.
+type client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func addClientFlags(fs *flag.FlagSet) *client {
+	c := &client{http: &http.Client{Timeout: 10 * time.Minute}}
+	fs.StringVar(&c.baseURL, "api", getEnv("NOVISSIMA_API_URL", "http://localhost:8080"), "base URL of the bot API")
+	fs.StringVar(&c.token, "token", os.Getenv("NOVISSIMA_API_TOKEN"), "API key or Supabase access token")
+	return c
+}
+
+func (c *client) do(req *http.Request) (*http.Response, error) {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return c.http.Do(req)
+}
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	c := addClientFlags(fs)
+	rowsPath := fs.String("rows", "", "CSV or JSON file of content rows (required)")
+	imagesPath := fs.String("images", "", "zip of the images named in the rows' image column")
+	allOrNothing := fs.Bool("all-or-nothing", false, "keep nothing unless every row imports")
+	fs.Parse(args)
+
+	if *rowsPath == "" {
+		fs.Usage()
+		return fmt.Errorf("-rows is required")
+	}
+
+	// Catch format and parse errors locally before uploading the images.
+	format, err := content.ImportFormatFor(*rowsPath)
+	if err != nil {
+		return err
+	}
+	rowsFile, err := os.Open(*rowsPath)
+	if err != nil {
+		return err
+	}
+	rows, err := content.ParseImportRows(rowsFile, format)
+	rowsFile.Close()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Importing %d rows from %s\n", len(rows), *rowsPath)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	if err := attachFile(writer, "rows", *rowsPath); err != nil {
+		return err
+	}
+	if *imagesPath != "" {
+		if err := attachFile(writer, "images", *imagesPath); err != nil {
+			return err
+		}
+	}
+	writer.WriteField("format", format)
+	if *allOrNothing {
+		writer.WriteField("all_or_nothing", "true")
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(c.baseURL, "/")+"/content/import", body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusUnprocessableEntity {
+		message, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("import failed: %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	var report content.ImportReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return fmt.Errorf("failed to read import report: %w", err)
+	}
+
+	for _, row := range report.Rows {
+		line := fmt.Sprintf("row %d: %s", row.Row, row.Status)
+		if row.ID != nil {
+			line += " " + row.ID.String()
+		}
+		if row.Error != "" {
+			line += ": " + row.Error
+		}
+		fmt.Println(line)
+	}
+	fmt.Printf("%d created, %d failed\n", report.Created, report.Failed)
+
+	if *allOrNothing && report.Failed > 0 {
+		return fmt.Errorf("%d rows failed, nothing was imported", report.Failed)
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d rows failed", report.Failed)
+	}
+	return nil
+}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	c := addClientFlags(fs)
+	output := fs.String("o", fmt.Sprintf("novissima-content-%s.zip", time.Now().Format("2006-01-02")), "file to write the archive to")
+	fs.Parse(args)
+
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(c.baseURL, "/")+"/content/export", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("export failed: %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	// Write to a temporary file first so a failed download never replaces
+	// an earlier backup.
+	tmp, err := os.CreateTemp(filepath.Dir(*output), ".contentctl-export-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, resp.Body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to download archive: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	manifest, err := readArchiveManifest(tmp.Name())
+	if err != nil {
+		return fmt.Errorf("downloaded archive is unreadable: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), *output); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d items to %s\n", len(manifest.Items), *output)
+	for _, warning := range manifest.Warnings {
+		fmt.Println("warning:", warning)
+	}
+	return nil
+}
+
+func readArchiveManifest(archivePath string) (content.ArchiveManifest, error) {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return content.ArchiveManifest{}, err
+	}
+	defer reader.Close()
+
+	file, err := reader.Open("manifest.json")
+	if err != nil {
+		return content.ArchiveManifest{}, err
+	}
+	defer file.Close()
+
+	var manifest content.ArchiveManifest
+	err = json.NewDecoder(file).Decode(&manifest)
+	return manifest, err
+}
+
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	c := addClientFlags(fs)
+	archivePath := fs.String("archive", "", "archive written by contentctl export (required)")
+	fs.Parse(args)
+
+	if *archivePath == "" {
+		fs.Usage()
+		return fmt.Errorf("-archive is required")
+	}
+
+	manifest, err := readArchiveManifest(*archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", *archivePath, err)
+	}
+	fmt.Printf("Restoring %d items exported at %s\n", len(manifest.Items), manifest.ExportedAt.Format(time.RFC3339))
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	if err := attachFile(writer, "archive", *archivePath); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(c.baseURL, "/")+"/content/restore", body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("restore failed: %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	var report content.RestoreReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return fmt.Errorf("failed to read restore report: %w", err)
+	}
+
+	for _, item := range report.Items {
+		if item.Status == content.RestoreFailed {
+			fmt.Printf("%s: failed: %s\n", item.ID, item.Error)
+		}
+	}
+	fmt.Printf("%d restored, %d already present, %d failed\n", report.Restored, report.Skipped, report.Failed)
+
+	if report.Failed > 0 {
+		return fmt.Errorf("%d items failed", report.Failed)
+	}
+	return nil
+}
+
+func attachFile(writer *multipart.Writer, field, filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	part, err := writer.CreateFormFile(field, filepath.Base(filePath))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, file)
+	return err
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
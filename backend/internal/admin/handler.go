@@ -0,0 +1,241 @@
+package admin
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"novissima/internal/users"
+)
+
+const (
+	defaultPerPage = 50
+	maxPerPage     = 200
+	exportPageSize = 500
+	historyLimit   = 50
+)
+
+type userPage struct {
+	Items   []users.User `json:"items"`
+	Total   int          `json:"total"`
+	Page    int          `json:"page"`
+	PerPage int          `json:"per_page"`
+}
+
+// HandleListUsers serves GET /admin/users. Filters are q (a phone number
+// fragment), active, language, and created_from/created_to as inclusive
+// YYYY-MM-DD dates; page and per_page paginate.
+func (s *Service) HandleListUsers(w http.ResponseWriter, r *http.Request) {
+	filter, err := userFilterFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	page, err := positiveInt(query.Get("page"), 1)
+	if err != nil {
+		http.Error(w, "Invalid page", http.StatusBadRequest)
+		return
+	}
+	perPage, err := positiveInt(query.Get("per_page"), defaultPerPage)
+	if err != nil {
+		http.Error(w, "Invalid per_page", http.StatusBadRequest)
+		return
+	}
+	if perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+	filter.Limit = perPage
+	filter.Offset = (page - 1) * perPage
+
+	found, total, err := s.userService.ListUsers(filter)
+	if err != nil {
+		http.Error(w, "Failed to list users", http.StatusInternalServerError)
+		return
+	}
+	if found == nil {
+		found = []users.User{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(userPage{
+		Items:   found,
+		Total:   total,
+		Page:    page,
+		PerPage: perPage,
+	})
+}
+
+// HandleExportUsers serves GET /admin/users/export as CSV. It takes the same
+// filters as HandleListUsers and returns every match.
+func (s *Service) HandleExportUsers(w http.ResponseWriter, r *http.Request) {
+	filter, err := userFilterFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Fetch everything before writing so a failure can still be reported
+	// with a proper status code.
+	var all []users.User
+	filter.Limit = exportPageSize
+	for {
+		found, _, err := s.userService.ListUsers(filter)
+		if err != nil {
+			http.Error(w, "Failed to export users", http.StatusInternalServerError)
+			return
+		}
+		all = append(all, found...)
+		if len(found) < exportPageSize {
+			break
+		}
+		filter.Offset += exportPageSize
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="users-%s.csv"`, time.Now().Format("2006-01-02")))
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"id", "phone_number", "active", "language", "timezone", "preferred_hour", "created_at", "updated_at"})
+	for _, user := range all {
+		writer.Write([]string{
+			user.ID.String(),
+			user.PhoneNumber,
+			strconv.FormatBool(user.Active),
+			user.Language,
+			user.Timezone,
+			strconv.Itoa(user.PreferredHour),
+			user.CreatedAt.UTC().Format(time.RFC3339),
+			user.UpdatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	writer.Flush()
+}
+
+func (s *Service) HandleGetUser(w http.ResponseWriter, r *http.Request) {
+	user, err := s.userService.GetUserByPhoneNumber(r.PathValue("phone"))
+	if errors.Is(err, users.ErrUserNotFound) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// HandleUpdateUser serves PATCH /admin/users/{phone} with a JSON body such
+// as {"active": false} or {"language": "la"}.
+func (s *Service) HandleUpdateUser(w http.ResponseWriter, r *http.Request) {
+	var changes UserChanges
+	if err := json.NewDecoder(r.Body).Decode(&changes); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	if changes.Active == nil && changes.Language == nil {
+		http.Error(w, "Nothing to update, expected active or language", http.StatusBadRequest)
+		return
+	}
+
+	if changes.Language != nil && !users.IsValidLanguage(*changes.Language) {
+		http.Error(w, "Invalid language, expected en, la or both", http.StatusBadRequest)
+		return
+	}
+
+	user, err := s.UpdateUser(r.PathValue("phone"), changes)
+	if errors.Is(err, users.ErrUserNotFound) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update user", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// HandleUserHistory serves GET /admin/users/{phone}/history with the user's
+// most recent commands and deliveries.
+func (s *Service) HandleUserHistory(w http.ResponseWriter, r *http.Request) {
+	limit, err := positiveInt(r.URL.Query().Get("limit"), historyLimit)
+	if err != nil {
+		http.Error(w, "Invalid limit", http.StatusBadRequest)
+		return
+	}
+
+	history, err := s.History(r.PathValue("phone"), limit)
+	if errors.Is(err, users.ErrUserNotFound) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to get user history", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// userFilterFromQuery reads the user filters shared by listing and export.
+// The returned error is meant to be shown to the client.
+func userFilterFromQuery(r *http.Request) (users.UserFilter, error) {
+	query := r.URL.Query()
+	filter := users.UserFilter{
+		Search:   strings.TrimSpace(query.Get("q")),
+		Language: strings.TrimSpace(query.Get("language")),
+	}
+
+	if raw := query.Get("active"); raw != "" {
+		active, err := strconv.ParseBool(raw)
+		if err != nil {
+			return users.UserFilter{}, errors.New("Invalid active, expected true or false")
+		}
+		filter.Active = &active
+	}
+
+	if filter.Language != "" && !users.IsValidLanguage(filter.Language) {
+		return users.UserFilter{}, errors.New("Invalid language, expected en, la or both")
+	}
+
+	if raw := query.Get("created_from"); raw != "" {
+		from, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return users.UserFilter{}, errors.New("Invalid created_from, expected YYYY-MM-DD")
+		}
+		filter.CreatedFrom = from
+	}
+
+	if raw := query.Get("created_to"); raw != "" {
+		to, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return users.UserFilter{}, errors.New("Invalid created_to, expected YYYY-MM-DD")
+		}
+		filter.CreatedBefore = to.AddDate(0, 0, 1)
+	}
+
+	return filter, nil
+}
+
+func positiveInt(raw string, fallback int) (int, error) {
+	if raw == "" {
+		return fallback, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 1 {
+		return 0, fmt.Errorf("invalid positive integer %q", raw)
+	}
+	return value, nil
+}
@@ -0,0 +1,125 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"novissima/internal/deliveries"
+	"novissima/internal/logging"
+	"novissima/internal/users"
+)
+
+// Service exposes subscriber management to admins. It acts on behalf of
+// users through the same users.Service calls the WhatsApp commands use.
+type Service struct {
+	userService     *users.Service
+	deliveryService *deliveries.Service
+	loggingService  *logging.Service
+}
+
+func NewService(userService *users.Service, deliveryService *deliveries.Service, loggingService *logging.Service) *Service {
+	return &Service{
+		userService:     userService,
+		deliveryService: deliveryService,
+		loggingService:  loggingService,
+	}
+}
+
+// UserChanges is an admin edit of a subscriber. Nil fields are left as they
+// are.
+type UserChanges struct {
+	Active   *bool   `json:"active"`
+	Language *string `json:"language"`
+}
+
+// UpdateUser applies changes to the user with phoneNumber and returns the
+// updated user.
+func (s *Service) UpdateUser(phoneNumber string, changes UserChanges) (users.User, error) {
+	user, err := s.userService.GetUserByPhoneNumber(phoneNumber)
+	if err != nil {
+		return users.User{}, err
+	}
+
+	if changes.Active != nil && *changes.Active != user.Active {
+		if err := s.userService.UpdateUserStatus(phoneNumber, *changes.Active); err != nil {
+			return users.User{}, fmt.Errorf("failed to update user status: %w", err)
+		}
+		if *changes.Active {
+			s.loggingService.LogUserActivated(user.ID)
+		} else {
+			s.loggingService.LogUserDeactivated(user.ID)
+		}
+	}
+
+	if changes.Language != nil && *changes.Language != user.Language {
+		if err := s.userService.UpdateUserLanguage(phoneNumber, *changes.Language); err != nil {
+			return users.User{}, fmt.Errorf("failed to update user language: %w", err)
+		}
+		s.loggingService.LogUserLanguageChanged(user.ID, *changes.Language)
+	}
+
+	log.Printf("Admin updated user %s", phoneNumber)
+	return s.userService.GetUserByPhoneNumber(phoneNumber)
+}
+
+// UserHistory is what a subscriber has sent us and what we have sent them.
+type UserHistory struct {
+	Commands   []Command             `json:"commands"`
+	Deliveries []deliveries.Delivery `json:"deliveries"`
+}
+
+type Command struct {
+	Command   string    `json:"command"`
+	Body      string    `json:"body"`
+	Reply     string    `json:"reply"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func commandFromLog(entry logging.LogEntry) (Command, error) {
+	var command Command
+	if err := json.Unmarshal([]byte(entry.Data), &command); err != nil {
+		return Command{}, err
+	}
+	command.CreatedAt = entry.CreatedAt
+	return command, nil
+}
+
+// History returns up to limit of the most recent commands and deliveries for
+// the user with phoneNumber.
+func (s *Service) History(phoneNumber string, limit int) (UserHistory, error) {
+	user, err := s.userService.GetUserByPhoneNumber(phoneNumber)
+	if err != nil {
+		return UserHistory{}, err
+	}
+
+	entries, err := s.loggingService.ListEvents(logging.LogFilter{
+		EventTypes:  []string{"command_received"},
+		PhoneNumber: user.PhoneNumber,
+		Limit:       limit,
+	})
+	if err != nil {
+		return UserHistory{}, err
+	}
+
+	commands := make([]Command, 0, len(entries))
+	for _, entry := range entries {
+		command, err := commandFromLog(entry)
+		if err != nil {
+			log.Printf("Skipping unreadable command log %s: %v", entry.ID, err)
+			continue
+		}
+		commands = append(commands, command)
+	}
+
+	delivered, err := s.deliveryService.UserHistory(user.ID, limit)
+	if err != nil {
+		return UserHistory{}, err
+	}
+	if delivered == nil {
+		delivered = []deliveries.Delivery{}
+	}
+
+	return UserHistory{Commands: commands, Deliveries: delivered}, nil
+}
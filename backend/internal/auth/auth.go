@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Role is what a caller of the admin API may do. Roles are ordered: each one
+// includes everything the roles before it can do.
+type Role string
+
+const (
+	RoleEditor   Role = "editor"
+	RoleReviewer Role = "reviewer"
+	RoleAdmin    Role = "admin"
+)
+
+var roleRank = map[Role]int{
+	RoleEditor:   1,
+	RoleReviewer: 2,
+	RoleAdmin:    3,
+}
+
+func ParseRole(value string) (Role, error) {
+	role := Role(strings.ToLower(strings.TrimSpace(value)))
+	if _, ok := roleRank[role]; !ok {
+		return "", fmt.Errorf("unknown role %q", value)
+	}
+	return role, nil
+}
+
+// Allows reports whether r is at least as privileged as required.
+func (r Role) Allows(required Role) bool {
+	return roleRank[r] >= roleRank[required]
+}
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Subject string
+	Email   string
+	Role    Role
+	Method  string
+}
+
+const (
+	MethodJWT    = "jwt"
+	MethodAPIKey = "api_key"
+)
+
+// APIKey is a static credential from config, for scripts and CI that cannot
+// sign in through Supabase.
+type APIKey struct {
+	Name string
+	Role Role
+	Key  string
+}
+
+// ParseAPIKeys parses entries of the form name:role:key.
+func ParseAPIKeys(entries []string) ([]APIKey, error) {
+	keys := make([]APIKey, 0, len(entries))
+	for _, entry := range entries {
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("invalid API key entry, expected name:role:key")
+		}
+		role, err := ParseRole(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid API key %s: %w", parts[0], err)
+		}
+		keys = append(keys, APIKey{Name: parts[0], Role: role, Key: parts[2]})
+	}
+	return keys, nil
+}
+
+var (
+	ErrMissingCredentials = errors.New("missing credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrNoRole             = errors.New("no role assigned")
+)
+
+// supabaseClaims are the parts of a Supabase access token we use. The role
+// is read from app_metadata, which only the service role can write; the
+// top-level role claim is always "authenticated".
+type supabaseClaims struct {
+	Email       string `json:"email"`
+	AppMetadata struct {
+		Role string `json:"role"`
+	} `json:"app_metadata"`
+	jwt.RegisteredClaims
+}
+
+func (s *Service) authenticate(token string) (Principal, error) {
+	if token == "" {
+		return Principal{}, ErrMissingCredentials
+	}
+
+	for _, key := range s.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(key.Key)) == 1 {
+			return Principal{Subject: key.Name, Role: key.Role, Method: MethodAPIKey}, nil
+		}
+	}
+
+	if s.jwtSecret == "" {
+		return Principal{}, ErrInvalidCredentials
+	}
+
+	var claims supabaseClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return []byte(s.jwtSecret), nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithAudience("authenticated"),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	principal := Principal{Subject: claims.Subject, Email: claims.Email, Method: MethodJWT}
+	if claims.AppMetadata.Role == "" {
+		return principal, ErrNoRole
+	}
+	role, err := ParseRole(claims.AppMetadata.Role)
+	if err != nil {
+		return principal, fmt.Errorf("%w: %v", ErrNoRole, err)
+	}
+	principal.Role = role
+	return principal, nil
+}
+
+type contextKey struct{}
+
+func withPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
+
+// PrincipalFrom returns the caller stored on ctx by Require.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(contextKey{}).(Principal)
+	return principal, ok
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"novissima/internal/logging"
+)
+
+type Service struct {
+	jwtSecret      string
+	apiKeys        []APIKey
+	loggingService *logging.Service
+}
+
+func NewService(jwtSecret string, apiKeys []APIKey, loggingService *logging.Service) *Service {
+	return &Service{
+		jwtSecret:      jwtSecret,
+		apiKeys:        apiKeys,
+		loggingService: loggingService,
+	}
+}
+
+// Require only lets callers with at least role through to next. The caller
+// is available to next through PrincipalFrom, and every request that
+// authenticates is logged with its outcome.
+func (s *Service) Require(role Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, err := s.authenticate(bearerToken(r))
+		switch {
+		case errors.Is(err, ErrNoRole):
+			s.logAction(principal, r, http.StatusForbidden)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		case err != nil:
+			log.Printf("Rejected %s %s from %s: %v", r.Method, r.URL.Path, r.RemoteAddr, err)
+			s.loggingService.LogAuthRejected(r.Method, r.URL.Path, err.Error(), r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if !principal.Role.Allows(role) {
+			s.logAction(principal, r, http.StatusForbidden)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r.WithContext(withPrincipal(r.Context(), principal)))
+		s.logAction(principal, r, recorder.status)
+	}
+}
+
+func (s *Service) logAction(principal Principal, r *http.Request, status int) {
+	if err := s.loggingService.LogAdminAction(principal.Subject, principal.Email, string(principal.Role), principal.Method, r.Method, r.URL.Path, status); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+}
+
+// bearerToken returns the token from an "Authorization: Bearer" header, which
+// carries either a Supabase JWT or a configured API key.
+func bearerToken(r *http.Request) string {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
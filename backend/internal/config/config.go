@@ -1,15 +1,21 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/robfig/cron/v3"
 )	
 
 type Config struct {
 	WhatsAppToken string
 	DatabaseURL   string	
+	DatabaseBackend string
 	SupabaseURL   string
 	SupabaseKey   string
 	SupabaseEmail string
@@ -21,6 +27,30 @@ type Config struct {
 	ContentBucketName string
 	TwilioContentSid string
 	TwilioMessagingServiceSid string
+	TwilioValidateSignatures bool
+	PublicBaseURL string
+	Messenger string
+	RotationThemes []string
+	RotationStartDate time.Time
+	RotationMinGap time.Duration
+	LiturgicalCalendarFile string
+	SchedulerEnabled bool
+	SchedulerLocation *time.Location
+	DailyBroadcastSchedule string
+	WeeklyDigestSchedule string
+	ImageStore string
+	LocalImageDir string
+	LocalImageBaseURL string
+	S3Endpoint string
+	S3Region string
+	S3Bucket string
+	S3AccessKeyID string
+	S3SecretAccessKey string
+	S3UseSSL bool
+	S3PublicBaseURL string
+	SupabaseJWTSecret string
+	AdminAPIKeys []string
+	ReviewerNumbers []string
 }
 
 func LoadConfig() (*Config, error) {
@@ -30,9 +60,35 @@ func LoadConfig() (*Config, error) {
             log.Println("Warning: .env file not found, skipping...")
         }
     }
+
+	rotationStartDate, err := time.Parse("2006-01-02", getEnv("ROTATION_START_DATE", "2025-07-01"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ROTATION_START_DATE: %w", err)
+	}
+	rotationMinGapDays, err := strconv.Atoi(getEnv("ROTATION_MIN_GAP_DAYS", "30"))
+	if err != nil || rotationMinGapDays < 0 {
+		return nil, fmt.Errorf("invalid ROTATION_MIN_GAP_DAYS: %q", os.Getenv("ROTATION_MIN_GAP_DAYS"))
+	}
+
+	schedulerLocation, err := time.LoadLocation(getEnv("SCHEDULER_TIMEZONE", "UTC"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SCHEDULER_TIMEZONE: %w", err)
+	}
+	// Schedules are checked even when the scheduler is disabled, so a typo
+	// does not wait to surface until it is turned on.
+	dailyBroadcastSchedule := getEnv("DAILY_BROADCAST_SCHEDULE", "*/5 * * * *")
+	if _, err := cron.ParseStandard(dailyBroadcastSchedule); err != nil {
+		return nil, fmt.Errorf("invalid DAILY_BROADCAST_SCHEDULE: %w", err)
+	}
+	weeklyDigestSchedule := getEnv("WEEKLY_DIGEST_SCHEDULE", "0 9 * * MON")
+	if _, err := cron.ParseStandard(weeklyDigestSchedule); err != nil {
+		return nil, fmt.Errorf("invalid WEEKLY_DIGEST_SCHEDULE: %w", err)
+	}
+
 	return &Config{
 		WhatsAppToken: os.Getenv("WHATSAPP_TOKEN"),
 		DatabaseURL:   os.Getenv("DATABASE_URL"),
+		DatabaseBackend: os.Getenv("DATABASE_BACKEND"),
 		SupabaseURL:   os.Getenv("SUPABASE_URL"),
 		SupabaseKey:   os.Getenv("SUPABASE_KEY"),
 		SupabaseEmail: os.Getenv("SUPABASE_EMAIL"),
@@ -44,5 +100,46 @@ func LoadConfig() (*Config, error) {
 		ContentBucketName: os.Getenv("CONTENT_BUCKET_NAME"),
 		TwilioContentSid: os.Getenv("TWILIO_CONTENT_SID"),
 		TwilioMessagingServiceSid: os.Getenv("TWILIO_MESSAGING_SERVICE_SID"),
+		TwilioValidateSignatures: os.Getenv("TWILIO_SKIP_SIGNATURE_VALIDATION") != "true",
+		PublicBaseURL: os.Getenv("PUBLIC_BASE_URL"),
+		Messenger: getEnv("MESSENGER", "twilio"),
+		RotationThemes: splitList(getEnv("ROTATION_THEMES", "death")),
+		RotationStartDate: rotationStartDate,
+		RotationMinGap: time.Duration(rotationMinGapDays) * 24 * time.Hour,
+		LiturgicalCalendarFile: os.Getenv("LITURGICAL_CALENDAR_FILE"),
+		SchedulerEnabled: os.Getenv("SCHEDULER_ENABLED") != "false",
+		SchedulerLocation: schedulerLocation,
+		DailyBroadcastSchedule: dailyBroadcastSchedule,
+		WeeklyDigestSchedule: weeklyDigestSchedule,
+		ImageStore: getEnv("IMAGE_STORE", "supabase"),
+		LocalImageDir: getEnv("LOCAL_IMAGE_DIR", "./images"),
+		LocalImageBaseURL: getEnv("LOCAL_IMAGE_BASE_URL", "http://localhost:8080/images"),
+		S3Endpoint: os.Getenv("S3_ENDPOINT"),
+		S3Region: os.Getenv("S3_REGION"),
+		S3Bucket: os.Getenv("S3_BUCKET"),
+		S3AccessKeyID: os.Getenv("S3_ACCESS_KEY_ID"),
+		S3SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		S3UseSSL: os.Getenv("S3_USE_SSL") != "false",
+		S3PublicBaseURL: os.Getenv("S3_PUBLIC_BASE_URL"),
+		SupabaseJWTSecret: os.Getenv("SUPABASE_JWT_SECRET"),
+		AdminAPIKeys: splitList(os.Getenv("ADMIN_API_KEYS")),
+		ReviewerNumbers: splitList(os.Getenv("REVIEWER_NUMBERS")),
 	}, nil
-} 
\ No newline at end of file
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,36 @@
+package config
+
+import "testing"
+
+func TestLoadConfigSchedules(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{name: "defaults"},
+		{name: "valid schedules", env: map[string]string{"DAILY_BROADCAST_SCHEDULE": "@hourly", "WEEKLY_DIGEST_SCHEDULE": "30 8 * * SUN"}},
+		{name: "bad daily schedule", env: map[string]string{"DAILY_BROADCAST_SCHEDULE": "every 5 minutes"}, wantErr: true},
+		{name: "bad weekly schedule", env: map[string]string{"WEEKLY_DIGEST_SCHEDULE": "0 9 * * MONDAY"}, wantErr: true},
+		{
+			name:    "checked when the scheduler is disabled",
+			env:     map[string]string{"SCHEDULER_ENABLED": "false", "DAILY_BROADCAST_SCHEDULE": "*/5 * * *"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ENV", "production")
+			t.Setenv("DAILY_BROADCAST_SCHEDULE", "")
+			t.Setenv("WEEKLY_DIGEST_SCHEDULE", "")
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			_, err := LoadConfig()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadConfig error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
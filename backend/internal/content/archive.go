@@ -0,0 +1,284 @@
+package content
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Content archives are zips holding manifest.json and the images it
+// references under images/. The version is bumped whenever the manifest
+// changes in a way older code cannot read.
+const (
+	ArchiveFormat  = "novissima-content"
+	ArchiveVersion = 1
+
+	manifestName = "manifest.json"
+	imagesDir    = "images/"
+)
+
+type ArchiveManifest struct {
+	Format     string        `json:"format"`
+	Version    int           `json:"version"`
+	ExportedAt time.Time     `json:"exported_at"`
+	Items      []ArchiveItem `json:"items"`
+	Warnings   []string      `json:"warnings,omitempty"`
+}
+
+// ArchiveItem is one content row. Image is the image's path inside the
+// archive, and ImageURL where it was stored when exported.
+type ArchiveItem struct {
+	Content
+	Image string `json:"image,omitempty"`
+}
+
+// imageClient downloads images for export. Every image store serves public
+// URLs, so a plain GET works whichever store the images are in.
+var imageClient = &http.Client{Timeout: 30 * time.Second}
+
+// Export writes the whole content library to w as a content archive. Images
+// that cannot be downloaded are left out and listed in the manifest's
+// warnings rather than failing the export.
+func (s *Service) Export(w io.Writer) (ArchiveManifest, error) {
+	contents, err := s.store.ListContent(ContentFilter{})
+	if err != nil {
+		return ArchiveManifest{}, fmt.Errorf("failed to list content: %w", err)
+	}
+
+	manifest := ArchiveManifest{
+		Format:     ArchiveFormat,
+		Version:    ArchiveVersion,
+		ExportedAt: time.Now().UTC(),
+		Items:      make([]ArchiveItem, 0, len(contents)),
+	}
+
+	archive := zip.NewWriter(w)
+	for _, content := range contents {
+		item := ArchiveItem{Content: content}
+
+		if imageURL := valueOf(content.ImageURL); imageURL != "" {
+			name := imagesDir + content.ID.String() + imageExt(imageURL)
+			if err := copyImage(archive, name, imageURL); err != nil {
+				log.Printf("Leaving image of %s out of the export: %v", content.ID, err)
+				manifest.Warnings = append(manifest.Warnings, fmt.Sprintf("%s: image not exported: %v", content.ID, err))
+			} else {
+				item.Image = name
+			}
+		}
+
+		manifest.Items = append(manifest.Items, item)
+	}
+
+	file, err := archive.Create(manifestName)
+	if err != nil {
+		return ArchiveManifest{}, fmt.Errorf("failed to write manifest: %w", err)
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return ArchiveManifest{}, fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	if err := archive.Close(); err != nil {
+		return ArchiveManifest{}, fmt.Errorf("failed to finish archive: %w", err)
+	}
+
+	log.Printf("Exported %d content items with %d warnings", len(manifest.Items), len(manifest.Warnings))
+	return manifest, nil
+}
+
+func copyImage(archive *zip.Writer, name, imageURL string) error {
+	resp, err := imageClient.Get(imageURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", imageURL, resp.Status)
+	}
+
+	// Images are already compressed, so store them as they are.
+	file, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, resp.Body)
+	return err
+}
+
+func imageExt(imageURL string) string {
+	if parsed, err := url.Parse(imageURL); err == nil {
+		return path.Ext(parsed.Path)
+	}
+	return path.Ext(imageURL)
+}
+
+const (
+	RestoreRestored = "restored"
+	RestoreSkipped  = "skipped"
+	RestoreFailed   = "failed"
+)
+
+type RestoreResult struct {
+	ID     uuid.UUID `json:"id"`
+	Status string    `json:"status"`
+	Error  string    `json:"error,omitempty"`
+}
+
+type RestoreReport struct {
+	Restored int             `json:"restored"`
+	Skipped  int             `json:"skipped"`
+	Failed   int             `json:"failed"`
+	Items    []RestoreResult `json:"items"`
+}
+
+// Restore loads a content archive. Images in the archive are uploaded to the
+// configured image store and image_url is rewritten to point there; IDs,
+// last_sent and timestamps are kept so the rotation carries on where it was.
+// Items whose ID already exists are skipped, which makes it safe to run a
+// restore again.
+func (s *Service) Restore(r io.ReaderAt, size int64) (RestoreReport, error) {
+	reader, err := zip.NewReader(r, size)
+	if err != nil {
+		return RestoreReport{}, fmt.Errorf("failed to open archive: %w", err)
+	}
+
+	files := map[string]*zip.File{}
+	for _, file := range reader.File {
+		files[file.Name] = file
+	}
+
+	manifest, err := readManifest(files[manifestName])
+	if err != nil {
+		return RestoreReport{}, err
+	}
+
+	report := RestoreReport{Items: make([]RestoreResult, 0, len(manifest.Items))}
+	for _, item := range manifest.Items {
+		result := RestoreResult{ID: item.ID, Status: RestoreRestored}
+		restored, err := s.restoreItem(item, files)
+		switch {
+		case err != nil:
+			result.Status = RestoreFailed
+			result.Error = err.Error()
+			report.Failed++
+		case !restored:
+			result.Status = RestoreSkipped
+			report.Skipped++
+		default:
+			report.Restored++
+		}
+		report.Items = append(report.Items, result)
+	}
+
+	log.Printf("Restored %d content items, skipped %d, failed %d", report.Restored, report.Skipped, report.Failed)
+	return report, nil
+}
+
+func readManifest(file *zip.File) (ArchiveManifest, error) {
+	if file == nil {
+		return ArchiveManifest{}, fmt.Errorf("archive has no %s", manifestName)
+	}
+
+	rc, err := file.Open()
+	if err != nil {
+		return ArchiveManifest{}, fmt.Errorf("failed to open manifest: %w", err)
+	}
+	defer rc.Close()
+
+	var manifest ArchiveManifest
+	if err := json.NewDecoder(rc).Decode(&manifest); err != nil {
+		return ArchiveManifest{}, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	if manifest.Format != ArchiveFormat {
+		return ArchiveManifest{}, fmt.Errorf("not a content archive (format %q)", manifest.Format)
+	}
+	if manifest.Version < 1 || manifest.Version > ArchiveVersion {
+		return ArchiveManifest{}, fmt.Errorf("unsupported archive version %d, this build reads up to %d", manifest.Version, ArchiveVersion)
+	}
+	return manifest, nil
+}
+
+// restoreItem restores one item, reporting false if it already exists.
+func (s *Service) restoreItem(item ArchiveItem, files map[string]*zip.File) (bool, error) {
+	if item.ID == uuid.Nil {
+		return false, errors.New("item has no id")
+	}
+
+	_, err := s.store.GetContent(item.ID)
+	if err == nil {
+		return false, nil
+	}
+	if !errors.Is(err, ErrContentNotFound) {
+		return false, fmt.Errorf("failed to check for existing content: %w", err)
+	}
+
+	// Archives from before the editorial workflow hold only broadcast
+	// content.
+	content := item.Content
+	if content.Status == "" {
+		content.Status = StatusApproved
+	} else if !IsValidStatus(content.Status) {
+		return false, fmt.Errorf("unknown status %q", content.Status)
+	}
+
+	// Without an image in the archive the original URL is kept, as it may
+	// still be reachable.
+	if item.Image != "" {
+		image, err := readArchiveImage(files[item.Image], item.Image)
+		if err != nil {
+			return false, err
+		}
+		processed, err := processImage(image.Data)
+		if err != nil {
+			return false, err
+		}
+		imageURL, info, err := s.uploadImage(processed, content.Theme)
+		if err != nil {
+			return false, err
+		}
+		content.ImageURL = &imageURL
+		content.ImageInfo = info
+		content.ImageHash = &processed.Hash
+	}
+	content.EnglishHash = textHash(content.TextEnglish, false)
+	content.LatinHash = textHash(valueOf(content.TextLatin), true)
+
+	if _, err := s.store.RestoreContent(content); err != nil {
+		if item.Image != "" {
+			s.deleteImages(*content.ImageURL, content.ThumbnailURL)
+		}
+		return false, fmt.Errorf("failed to restore content: %w", err)
+	}
+	return true, nil
+}
+
+func readArchiveImage(file *zip.File, name string) (*Image, error) {
+	if file == nil {
+		return nil, fmt.Errorf("image %q is not in the archive", name)
+	}
+
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image %q: %w", name, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, maxUploadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image %q: %w", name, err)
+	}
+
+	return &Image{Filename: path.Base(name), Data: data}, nil
+}
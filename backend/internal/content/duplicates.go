@@ -0,0 +1,380 @@
+package content
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"math/bits"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"golang.org/x/text/unicode/norm"
+)
+
+// ContentHashes fingerprint content so the same quote or image uploaded
+// twice can be spotted. Text hashes are of the normalized text, and the
+// image hash is a perceptual hash that survives re-encoding and resizing.
+type ContentHashes struct {
+	EnglishHash *string `json:"english_hash"`
+	LatinHash   *string `json:"latin_hash"`
+	ImageHash   *string `json:"image_hash"`
+}
+
+// imageHashDistance is how many of the 64 image hash bits may differ for two
+// images to count as the same picture.
+const imageHashDistance = 6
+
+// Fields a duplicate can match on.
+const (
+	MatchEnglish = "text_english"
+	MatchLatin   = "text_latin"
+	MatchImage   = "image"
+)
+
+// normalizeText reduces text to its lowercase letters and digits, without
+// accents. Latin spelling also varies between u and v and between i and j,
+// and in whether ae and oe are written as ligatures.
+func normalizeText(text string, latin bool) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(text)) {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			continue
+		}
+		if latin {
+			switch r {
+			case 'v':
+				r = 'u'
+			case 'j':
+				r = 'i'
+			case 'æ':
+				b.WriteString("ae")
+				continue
+			case 'œ':
+				b.WriteString("oe")
+				continue
+			}
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func textHash(text string, latin bool) *string {
+	normalized := normalizeText(text, latin)
+	if normalized == "" {
+		return nil
+	}
+	sum := sha256.Sum256([]byte(normalized))
+	hash := hex.EncodeToString(sum[:])
+	return &hash
+}
+
+// imageHash is a difference hash: each bit says whether a pixel of a 9x8
+// grayscale thumbnail is brighter than its right-hand neighbour.
+func imageHash(img *image.RGBA) string {
+	small := scale(img, 9, 8)
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if luminance(small, x, y) > luminance(small, x+1, y) {
+				hash |= 1 << (y*8 + x)
+			}
+		}
+	}
+	return fmt.Sprintf("%016x", hash)
+}
+
+func luminance(img *image.RGBA, x, y int) int {
+	p := img.Pix[y*img.Stride+x*4:]
+	return 299*int(p[0]) + 587*int(p[1]) + 114*int(p[2])
+}
+
+func similarImages(a, b *string) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	x, errX := strconv.ParseUint(*a, 16, 64)
+	y, errY := strconv.ParseUint(*b, 16, 64)
+	if errX != nil || errY != nil {
+		return false
+	}
+	return bits.OnesCount64(x^y) <= imageHashDistance
+}
+
+// hashesOf returns the text hashes of c computed from its current text, and
+// its stored image hash. Computing the text hashes covers rows saved before
+// hashes were stored.
+func hashesOf(c Content) ContentHashes {
+	return ContentHashes{
+		EnglishHash: textHash(c.TextEnglish, false),
+		LatinHash:   textHash(valueOf(c.TextLatin), true),
+		ImageHash:   c.ImageHash,
+	}
+}
+
+// matchingFields lists what a and b have in common.
+func matchingFields(a, b ContentHashes) []string {
+	var fields []string
+	if a.EnglishHash != nil && b.EnglishHash != nil && *a.EnglishHash == *b.EnglishHash {
+		fields = append(fields, MatchEnglish)
+	}
+	if a.LatinHash != nil && b.LatinHash != nil && *a.LatinHash == *b.LatinHash {
+		fields = append(fields, MatchLatin)
+	}
+	if similarImages(a.ImageHash, b.ImageHash) {
+		fields = append(fields, MatchImage)
+	}
+	return fields
+}
+
+// DuplicateMatch is existing content that new content duplicates.
+type DuplicateMatch struct {
+	ContentID uuid.UUID `json:"content_id"`
+	Theme     string    `json:"theme"`
+	Status    string    `json:"status"`
+	Fields    []string  `json:"fields"`
+}
+
+// DuplicateError rejects content that duplicates existing content.
+type DuplicateError struct {
+	Matches []DuplicateMatch
+}
+
+func (e *DuplicateError) Error() string {
+	match := e.Matches[0]
+	message := fmt.Sprintf("duplicate of content %s (same %s)", match.ContentID, strings.Join(match.Fields, ", "))
+	if len(e.Matches) > 1 {
+		message += fmt.Sprintf(" and %d more", len(e.Matches)-1)
+	}
+	return message
+}
+
+// findDuplicates returns the content that shares a text or image with
+// hashes. Text matches are looked up by their stored hashes; images match
+// by distance rather than equality, so those are compared one by one.
+func (s *Service) findDuplicates(hashes ContentHashes) ([]DuplicateMatch, error) {
+	candidates, err := s.store.FindContentByTextHash(hashes.EnglishHash, hashes.LatinHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for duplicates: %w", err)
+	}
+	if hashes.ImageHash != nil {
+		hasImage := true
+		withImages, err := s.store.ListContent(ContentFilter{HasImage: &hasImage})
+		if err != nil {
+			return nil, fmt.Errorf("failed to check for duplicates: %w", err)
+		}
+		candidates = append(candidates, withImages...)
+	}
+
+	var matches []DuplicateMatch
+	seen := map[uuid.UUID]bool{}
+	for _, c := range candidates {
+		if seen[c.ID] {
+			continue
+		}
+		seen[c.ID] = true
+		if fields := matchingFields(hashes, c.ContentHashes); len(fields) > 0 {
+			matches = append(matches, DuplicateMatch{ContentID: c.ID, Theme: c.Theme, Status: c.Status, Fields: fields})
+		}
+	}
+	return matches, nil
+}
+
+// DuplicateItem is one member of a DuplicateCluster.
+type DuplicateItem struct {
+	ID          uuid.UUID `json:"id"`
+	Theme       string    `json:"theme"`
+	Status      string    `json:"status"`
+	TextEnglish string    `json:"text_english"`
+}
+
+// DuplicateCluster is a group of content linked by shared texts or images.
+// Fields lists every kind of match found within the group.
+type DuplicateCluster struct {
+	Fields []string        `json:"fields"`
+	Items  []DuplicateItem `json:"items"`
+}
+
+// ScanDuplicates groups the whole library into clusters of duplicates. It
+// only reads: rows saved before images were hashed are compared by text
+// alone until BackfillHashes has run.
+func (s *Service) ScanDuplicates() ([]DuplicateCluster, error) {
+	contents, err := s.store.ListContent(ContentFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list content: %w", err)
+	}
+
+	hashes := make([]ContentHashes, len(contents))
+	for i, c := range contents {
+		hashes[i] = hashesOf(c)
+	}
+
+	// Union-find over every matching pair.
+	parent := make([]int, len(contents))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	fields := map[int]map[string]bool{}
+	union := func(i, j int, field string) {
+		a, b := find(i), find(j)
+		if fields[a] == nil {
+			fields[a] = map[string]bool{}
+		}
+		fields[a][field] = true
+		if a == b {
+			return
+		}
+		parent[b] = a
+		for f := range fields[b] {
+			fields[a][f] = true
+		}
+		delete(fields, b)
+	}
+
+	// Equal text hashes are grouped by hash, linking each row to the first
+	// one seen with that hash.
+	firstWith := map[string]int{}
+	for i, h := range hashes {
+		for field, hash := range map[string]*string{MatchEnglish: h.EnglishHash, MatchLatin: h.LatinHash} {
+			if hash == nil {
+				continue
+			}
+			key := field + ":" + *hash
+			if first, ok := firstWith[key]; ok {
+				union(first, i, field)
+			} else {
+				firstWith[key] = i
+			}
+		}
+	}
+
+	// Similar images are not equal, so they are compared pairwise.
+	var withImage []int
+	for i, h := range hashes {
+		if h.ImageHash != nil {
+			withImage = append(withImage, i)
+		}
+	}
+	for x, i := range withImage {
+		for _, j := range withImage[x+1:] {
+			if similarImages(hashes[i].ImageHash, hashes[j].ImageHash) {
+				union(i, j, MatchImage)
+			}
+		}
+	}
+
+	groups := map[int][]int{}
+	for i := range contents {
+		root := find(i)
+		groups[root] = append(groups[root], i)
+	}
+
+	clusters := []DuplicateCluster{}
+	for root, members := range groups {
+		if len(members) < 2 {
+			continue
+		}
+		cluster := DuplicateCluster{}
+		for field := range fields[root] {
+			cluster.Fields = append(cluster.Fields, field)
+		}
+		sort.Strings(cluster.Fields)
+		for _, i := range members {
+			c := contents[i]
+			cluster.Items = append(cluster.Items, DuplicateItem{ID: c.ID, Theme: c.Theme, Status: c.Status, TextEnglish: c.TextEnglish})
+		}
+		clusters = append(clusters, cluster)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].Items[0].ID.String() < clusters[j].Items[0].ID.String()
+	})
+
+	log.Printf("Duplicate scan found %d clusters in %d content items", len(clusters), len(contents))
+	return clusters, nil
+}
+
+// BackfillHashes stores hashes on rows saved before content was hashed, or
+// whose hashes are out of date, downloading images that were never hashed.
+// It returns how many rows were updated.
+func (s *Service) BackfillHashes() (int, error) {
+	contents, err := s.store.ListContent(ContentFilter{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list content: %w", err)
+	}
+
+	updated := 0
+	for _, c := range contents {
+		hashes := hashesOf(c)
+		if hashes.ImageHash == nil && valueOf(c.ImageURL) != "" {
+			hash, err := downloadImageHash(*c.ImageURL)
+			if err != nil {
+				log.Printf("Failed to hash image of %s: %v", c.ID, err)
+			} else {
+				hashes.ImageHash = &hash
+			}
+		}
+
+		if valueOf(hashes.EnglishHash) == valueOf(c.EnglishHash) &&
+			valueOf(hashes.LatinHash) == valueOf(c.LatinHash) &&
+			valueOf(hashes.ImageHash) == valueOf(c.ImageHash) {
+			continue
+		}
+		if err := s.store.UpdateContentHashes(c.ID, hashes); err != nil {
+			log.Printf("Failed to save hashes of %s: %v", c.ID, err)
+			continue
+		}
+		updated++
+	}
+
+	log.Printf("Backfilled hashes on %d of %d content items", updated, len(contents))
+	return updated, nil
+}
+
+// downloadImageHash hashes an image that was stored before images were
+// hashed on upload.
+func downloadImageHash(imageURL string) (string, error) {
+	resp, err := imageClient.Get(imageURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET %s: %s", imageURL, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxUploadSize+1))
+	if err != nil {
+		return "", err
+	}
+
+	// Check the dimensions first, as decoding allocates the whole image.
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return "", fmt.Errorf("image dimensions too large (%dx%d)", config.Width, config.Height)
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	return imageHash(orient(toRGBA(decoded), jpegOrientation(data))), nil
+}
@@ -0,0 +1,108 @@
+package content_test
+
+import (
+	"slices"
+	"testing"
+
+	"novissima/internal/content"
+)
+
+func TestScanDuplicates(t *testing.T) {
+	service, store := newTestService(t)
+	latin := func(s string) *string { return &s }
+
+	rows := []content.ContentCreate{
+		{TextEnglish: "Remember death.", Theme: "death"},
+		{TextEnglish: "REMEMBER, death!", Theme: "judgment"},
+		{TextEnglish: "Vanity of vanities.", TextLatin: latin("Vanitas vanitatum."), Theme: "death"},
+		{TextEnglish: "All is vanity.", TextLatin: latin("Uanitas uanitatum"), Theme: "death"},
+		{TextEnglish: "Dust thou art.", Theme: "death"},
+	}
+	ids := make([]string, len(rows))
+	for i, row := range rows {
+		c, err := store.InsertContent(row)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = c.ID.String()
+	}
+
+	clusters, err := service.ScanDuplicates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each cluster is keyed by the one field its members share.
+	want := map[string][]string{
+		content.MatchEnglish: {ids[0], ids[1]},
+		content.MatchLatin:   {ids[2], ids[3]},
+	}
+	if len(clusters) != len(want) {
+		t.Fatalf("found %d clusters, want %d: %+v", len(clusters), len(want), clusters)
+	}
+	for _, cluster := range clusters {
+		if len(cluster.Fields) != 1 {
+			t.Fatalf("cluster fields = %v, want one", cluster.Fields)
+		}
+		members, ok := want[cluster.Fields[0]]
+		if !ok {
+			t.Fatalf("unexpected cluster on %v", cluster.Fields)
+		}
+		var got []string
+		for _, item := range cluster.Items {
+			got = append(got, item.ID.String())
+		}
+		if !slices.Equal(sorted(got), sorted(members)) {
+			t.Errorf("%s cluster = %v, want %v", cluster.Fields[0], got, members)
+		}
+	}
+}
+
+func sorted(ids []string) []string {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	return ids
+}
+
+func TestBackfillHashes(t *testing.T) {
+	service, store := newTestService(t)
+
+	first, err := store.InsertContent(content.ContentCreate{TextEnglish: "Remember death.", Theme: "death"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.InsertContent(content.ContentCreate{TextEnglish: "Dust thou art.", Theme: "death"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		wantUpdated int
+	}{
+		{name: "rows without hashes are updated", wantUpdated: 2},
+		{name: "a second run has nothing to do", wantUpdated: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, err := service.BackfillHashes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if updated != tt.wantUpdated {
+				t.Errorf("updated %d rows, want %d", updated, tt.wantUpdated)
+			}
+		})
+	}
+
+	stored, err := store.GetContent(first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, err := store.FindContentByTextHash(stored.EnglishHash, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].ID != first.ID {
+		t.Errorf("lookup by the backfilled hash found %d rows, want %s", len(found), first.ID)
+	}
+}
@@ -2,9 +2,19 @@ package content
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"mime/multipart"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
+
+	"novissima/internal/auth"
+
+	"github.com/google/uuid"
 )
 
 func (s *Service) HandleCreateContent(w http.ResponseWriter, r *http.Request) {
@@ -18,52 +28,568 @@ func (s *Service) HandleCreateContent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	textEnglish := strings.TrimSpace(r.FormValue("textEnglish"))
-	textLatin := strings.TrimSpace(r.FormValue("textLatin"))
-	theme := strings.TrimSpace(r.FormValue("theme"))
-	imageSource := strings.TrimSpace(r.FormValue("imageSource"))
-	textSource := strings.TrimSpace(r.FormValue("textSource"))
-	if textEnglish == "" {
+	newContent := NewContent{
+		TextEnglish: strings.TrimSpace(r.FormValue("textEnglish")),
+		TextLatin:   strings.TrimSpace(r.FormValue("textLatin")),
+		Theme:       strings.TrimSpace(r.FormValue("theme")),
+		ImageSource: strings.TrimSpace(r.FormValue("imageSource")),
+		TextSource:  strings.TrimSpace(r.FormValue("textSource")),
+	}
+	if err := newContent.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := imageFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if file != nil {
+		defer file.Close()
+	}
+
+	allowDuplicate := r.FormValue("allowDuplicate") == "true"
+	content, duplicates, err := s.AddContent(newContent.TextEnglish, newContent.TextLatin, file, header, newContent.Theme, newContent.ImageSource, newContent.TextSource, allowDuplicate)
+	var imageErr *ImageError
+	if errors.As(err, &imageErr) {
+		http.Error(w, imageErr.Message, http.StatusBadRequest)
+		return
+	}
+	var duplicateErr *DuplicateError
+	if errors.As(err, &duplicateErr) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":    "This content duplicates existing content. Send allowDuplicate=true to add it anyway.",
+			"duplicates": duplicateErr.Matches,
+		})
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to add content", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"message": "Successfully added content as a draft: " + content.TextEnglish,
+		"content": content,
+	}
+	if len(duplicates) > 0 {
+		response["duplicates"] = duplicates
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleScanDuplicates serves GET /content/duplicates with the clusters of
+// duplicate content in the library.
+func (s *Service) HandleScanDuplicates(w http.ResponseWriter, r *http.Request) {
+	clusters, err := s.ScanDuplicates()
+	if err != nil {
+		http.Error(w, "Failed to scan for duplicates", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(clusters)
+}
+
+// HandleBackfillHashes serves POST /content/duplicates/backfill, which hashes
+// older rows, downloading their images, so duplicate checks cover them.
+func (s *Service) HandleBackfillHashes(w http.ResponseWriter, r *http.Request) {
+	updated, err := s.BackfillHashes()
+	if err != nil {
+		http.Error(w, "Failed to backfill hashes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"updated": updated})
+}
+
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+type contentPage struct {
+	Items   []Content `json:"items"`
+	Total   int       `json:"total"`
+	Page    int       `json:"page"`
+	PerPage int       `json:"per_page"`
+}
+
+// HandleListContent serves GET /content. It accepts page and per_page for
+// pagination and theme, has_latin, has_image and sent as filters.
+func (s *Service) HandleListContent(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	page, err := positiveInt(query.Get("page"), 1)
+	if err != nil {
+		http.Error(w, "Invalid page", http.StatusBadRequest)
+		return
+	}
+	perPage, err := positiveInt(query.Get("per_page"), defaultPerPage)
+	if err != nil {
+		http.Error(w, "Invalid per_page", http.StatusBadRequest)
+		return
+	}
+	if perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+
+	status := strings.TrimSpace(query.Get("status"))
+	if status != "" && !IsValidStatus(status) {
+		http.Error(w, "Invalid status, expected draft, in_review, approved or retired", http.StatusBadRequest)
+		return
+	}
+
+	filter := ContentFilter{
+		Theme:       strings.TrimSpace(query.Get("theme")),
+		Status:      status,
+		Limit:       perPage,
+		Offset:      (page - 1) * perPage,
+		NewestFirst: true,
+	}
+	for name, target := range map[string]**bool{
+		"has_latin": &filter.HasLatin,
+		"has_image": &filter.HasImage,
+		"sent":      &filter.Sent,
+	} {
+		value, err := optionalBool(query.Get(name))
+		if err != nil {
+			http.Error(w, "Invalid "+name+", expected true or false", http.StatusBadRequest)
+			return
+		}
+		*target = value
+	}
+
+	contents, total, err := s.ListContent(filter)
+	if err != nil {
+		http.Error(w, "Failed to list content", http.StatusInternalServerError)
+		return
+	}
+	if contents == nil {
+		contents = []Content{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(contentPage{
+		Items:   contents,
+		Total:   total,
+		Page:    page,
+		PerPage: perPage,
+	})
+}
+
+func (s *Service) HandleGetContent(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid content id", http.StatusBadRequest)
+		return
+	}
+
+	content, err := s.GetContent(id)
+	if errors.Is(err, ErrContentNotFound) {
+		http.Error(w, "Content not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to get content", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(content)
+}
+
+// HandleUpdateContent serves PUT and PATCH on /content/{id}. Both take the
+// same fields as HandleCreateContent. PUT replaces every text field, so
+// omitted optional fields are cleared, while PATCH only touches the fields
+// that are present. In both cases an uploaded image replaces the current one
+// and removeImage=true drops it. Editing approved content sends it back to
+// review.
+func (s *Service) HandleUpdateContent(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid content id", http.StatusBadRequest)
+		return
+	}
+
+	if err := r.ParseMultipartForm(32 << 20); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	replace := r.Method == http.MethodPut
+	changes := ContentChanges{
+		TextEnglish: formField(r, "textEnglish", replace),
+		TextLatin:   formField(r, "textLatin", replace),
+		Theme:       formField(r, "theme", replace),
+		ImageSource: formField(r, "imageSource", replace),
+		TextSource:  formField(r, "textSource", replace),
+		RemoveImage: r.PostForm.Get("removeImage") == "true",
+	}
+
+	if changes.TextEnglish != nil && *changes.TextEnglish == "" {
 		http.Error(w, "textEnglish is required", http.StatusBadRequest)
 		return
 	}
 
-	if theme == "" {
+	if changes.Theme != nil && *changes.Theme == "" {
 		http.Error(w, "theme is required", http.StatusBadRequest)
 		return
 	}
 
-	var file multipart.File
-	var header *multipart.FileHeader
-	var err error
-	
-	if file, header, err = r.FormFile("image"); err != nil {
-		file = nil
-		header = nil
-	} else {
-
+	file, header, err := imageFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if file != nil {
 		defer file.Close()
-		
-		if header.Size > 5<<20 {
-			http.Error(w, "Image file too large (max 5MB)", http.StatusBadRequest)
+	}
+
+	content, err := s.UpdateContent(id, changes, file, header, actorFrom(r))
+	var imageErr *ImageError
+	if errors.As(err, &imageErr) {
+		http.Error(w, imageErr.Message, http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, ErrContentNotFound) {
+		http.Error(w, "Content not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, ErrInvalidTransition) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update content", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(content)
+}
+
+// HandleDeleteContent serves DELETE /content/{id}, which is for admins only:
+// deleting skips the editorial workflow, so everyone else retires content
+// instead.
+func (s *Service) HandleDeleteContent(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid content id", http.StatusBadRequest)
+		return
+	}
+
+	err = s.DeleteContent(id)
+	if errors.Is(err, ErrContentNotFound) {
+		http.Error(w, "Content not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete content", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleListOverrides serves GET /admin/overrides. The optional from and to
+// query parameters are inclusive YYYY-MM-DD dates.
+func (s *Service) HandleListOverrides(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := OverrideFilter{From: query.Get("from"), To: query.Get("to")}
+	for _, bound := range []string{filter.From, filter.To} {
+		if _, err := time.Parse(overrideDateFormat, bound); bound != "" && err != nil {
+			http.Error(w, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
 			return
 		}
+	}
 
-		contentType := header.Header.Get("Content-Type")
-		if !isValidImageType(contentType) {
-			http.Error(w, "Invalid image type. Only JPEG, PNG, and GIF are allowed", http.StatusBadRequest)
+	overrides, err := s.ListOverrides(filter)
+	if err != nil {
+		http.Error(w, "Failed to list overrides", http.StatusInternalServerError)
+		return
+	}
+	if overrides == nil {
+		overrides = []ScheduleOverride{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(overrides)
+}
+
+type overrideResponse struct {
+	Override ScheduleOverride `json:"override"`
+	Warnings []string         `json:"warnings,omitempty"`
+}
+
+// HandleCreateOverride serves POST /admin/overrides with a JSON body of date,
+// content_id and optional language and note.
+func (s *Service) HandleCreateOverride(w http.ResponseWriter, r *http.Request) {
+	var create OverrideCreate
+	if err := json.NewDecoder(r.Body).Decode(&create); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if create.Language != nil && *create.Language == "" {
+		create.Language = nil
+	}
+	if err := create.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// created_by is always the caller, never taken from the body.
+	create.CreatedBy = optional(actorFrom(r))
+
+	override, warnings, err := s.AddOverride(create)
+	if errors.Is(err, ErrContentNotFound) {
+		http.Error(w, "Content not found", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, ErrOverrideExists) {
+		http.Error(w, "An override already exists for that date and language", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to add override", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(overrideResponse{Override: override, Warnings: warnings})
+}
+
+func (s *Service) HandleDeleteOverride(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid override id", http.StatusBadRequest)
+		return
+	}
+
+	err = s.DeleteOverride(id)
+	if errors.Is(err, ErrOverrideNotFound) {
+		http.Error(w, "Override not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete override", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type transitionRequest struct {
+	Comment string `json:"comment"`
+}
+
+// HandleTransition returns the handler for POST /content/{id}/{action}, which
+// applies a workflow action and responds with the updated content. Reject
+// takes a JSON body with the comment for the editor.
+func (s *Service) HandleTransition(action string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "Invalid content id", http.StatusBadRequest)
+			return
+		}
+
+		var request transitionRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+
+		content, err := s.Transition(id, action, actorFrom(r), strings.TrimSpace(request.Comment))
+		switch {
+		case errors.Is(err, ErrContentNotFound):
+			http.Error(w, "Content not found", http.StatusNotFound)
+			return
+		case errors.Is(err, ErrCommentRequired):
+			http.Error(w, "A comment is required to reject content", http.StatusBadRequest)
+			return
+		case errors.Is(err, ErrInvalidTransition):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, "Failed to update content status", http.StatusInternalServerError)
 			return
 		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(content)
 	}
+}
 
-	content, err := s.AddContent(textEnglish, textLatin, file, header, theme, imageSource, textSource)
+// actorFrom names the authenticated caller for audit records.
+func actorFrom(r *http.Request) string {
+	principal, ok := auth.PrincipalFrom(r.Context())
+	if !ok {
+		return ""
+	}
+	if principal.Email != "" {
+		return principal.Email
+	}
+	return principal.Subject
+}
+
+// imageFromRequest returns the uploaded image, or nil if the request has
+// none. The returned error is meant to be shown to the client.
+func imageFromRequest(r *http.Request) (multipart.File, *multipart.FileHeader, error) {
+	file, header, err := r.FormFile("image")
 	if err != nil {
-		http.Error(w, "Failed to add content", http.StatusInternalServerError)
+		return nil, nil, nil
+	}
+
+	if err := checkUploadSize(header.Size); err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	return file, header, nil
+}
+
+// formField returns the trimmed value of a form field. Absent fields are
+// nil unless required is set, in which case they read as "".
+func formField(r *http.Request, name string, required bool) *string {
+	values, ok := r.PostForm[name]
+	if !ok && !required {
+		return nil
+	}
+
+	value := ""
+	if len(values) > 0 {
+		value = strings.TrimSpace(values[0])
+	}
+	return &value
+}
+
+func positiveInt(raw string, fallback int) (int, error) {
+	if raw == "" {
+		return fallback, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 1 {
+		return 0, fmt.Errorf("invalid positive integer %q", raw)
+	}
+	return value, nil
+}
+
+func optionalBool(raw string) (*bool, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// HandleImportContent serves POST /content/import. The multipart form
+// carries a rows file (CSV or JSON, told apart by its extension unless format
+// is given), an optional images zip, and all_or_nothing=true to keep nothing
+// unless every row is imported. The response is a per-row report; it is sent
+// with 422 when an all-or-nothing import was abandoned.
+func (s *Service) HandleImportContent(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": "Successfully added content: " + content.TextEnglish,
-	})
+	rowsFile, rowsHeader, err := r.FormFile("rows")
+	if err != nil {
+		http.Error(w, "rows file is required", http.StatusBadRequest)
+		return
+	}
+	defer rowsFile.Close()
+
+	format := strings.ToLower(strings.TrimSpace(r.FormValue("format")))
+	if format == "" {
+		format, err = ImportFormatFor(rowsHeader.Filename)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	rows, err := ParseImportRows(rowsFile, format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(rows) == 0 {
+		http.Error(w, "rows file has no rows", http.StatusBadRequest)
+		return
+	}
+
+	var archive *ImageArchive
+	if imagesFile, imagesHeader, err := r.FormFile("images"); err == nil {
+		defer imagesFile.Close()
+
+		archive, err = OpenImageArchive(imagesFile, imagesHeader.Size)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	allOrNothing := r.FormValue("all_or_nothing") == "true"
+	report := s.Import(rows, archive, allOrNothing)
+
+	status := http.StatusOK
+	if allOrNothing && report.Failed > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
+
+// HandleExportContent serves GET /content/export, streaming the whole
+// library as a content archive.
+func (s *Service) HandleExportContent(w http.ResponseWriter, r *http.Request) {
+	filename := fmt.Sprintf("novissima-content-%s.zip", time.Now().Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	// Once the archive has started streaming the status can no longer
+	// change, so a failure here leaves the client with a truncated zip.
+	if _, err := s.Export(w); err != nil {
+		log.Printf("Content export failed: %v", err)
+	}
+}
+
+// HandleRestoreContent serves POST /content/restore with the archive in the
+// multipart field "archive", and responds with a per-item report.
+func (s *Service) HandleRestoreContent(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("archive")
+	if err != nil {
+		http.Error(w, "archive file is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	report, err := s.Restore(file, header.Size)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
 }
@@ -0,0 +1,341 @@
+package content
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// Uploaded images are re-encoded before they are stored. That drops EXIF
+// and GPS metadata, and lets oversized images be scaled down to fit
+// WhatsApp's media limits. Only JPEG and PNG are accepted: WhatsApp shows
+// GIFs as a still frame at best.
+const (
+	maxUploadSize      = 20 << 20
+	maxImageSize       = 5 << 20
+	maxImagePixels     = 50_000_000
+	maxImageDimension  = 1600
+	thumbnailDimension = 320
+	jpegQuality        = 85
+	thumbnailQuality   = 75
+)
+
+// ImageError is an image that cannot be used. Message is meant to be shown
+// to the editor.
+type ImageError struct {
+	Message string
+}
+
+func (e *ImageError) Error() string {
+	return e.Message
+}
+
+// ImageInfo describes the stored image of a content item.
+type ImageInfo struct {
+	ThumbnailURL *string `json:"thumbnail_url"`
+	ImageWidth   *int    `json:"image_width"`
+	ImageHeight  *int    `json:"image_height"`
+	ImageSize    *int64  `json:"image_size"`
+}
+
+type processedImage struct {
+	Data        []byte
+	ContentType string
+	Ext         string
+	Width       int
+	Height      int
+	Thumbnail   []byte
+	Hash        string
+}
+
+// checkUploadSize rejects files too large to be worth processing.
+func checkUploadSize(size int64) error {
+	if size > maxUploadSize {
+		return &ImageError{Message: fmt.Sprintf("Image file too large (max %dMB)", maxUploadSize>>20)}
+	}
+	return nil
+}
+
+// processImage decodes data, whatever its file name or declared type says,
+// and returns it re-encoded along with a JPEG thumbnail.
+func processImage(data []byte) (*processedImage, error) {
+	if err := checkUploadSize(int64(len(data))); err != nil {
+		return nil, err
+	}
+
+	format := ""
+	switch detected := http.DetectContentType(data); detected {
+	case "image/jpeg":
+		format = "jpeg"
+	case "image/png":
+		format = "png"
+	case "image/gif":
+		return nil, &ImageError{Message: "GIF images are not supported. Please upload a JPEG or PNG"}
+	default:
+		return nil, &ImageError{Message: fmt.Sprintf("Unsupported image format (%s). Please upload a JPEG or PNG", detected)}
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, &ImageError{Message: "Image could not be read, the file may be damaged"}
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, &ImageError{Message: fmt.Sprintf("Image dimensions too large (%dx%d)", config.Width, config.Height)}
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, &ImageError{Message: "Image could not be read, the file may be damaged"}
+	}
+
+	img := toRGBA(decoded)
+	if format == "jpeg" {
+		// The orientation tag goes with the rest of the metadata, so apply it
+		// to the pixels first.
+		img = orient(img, jpegOrientation(data))
+	}
+	img = fit(img, maxImageDimension)
+
+	encoded, format, err := encodeWithin(img, format, maxImageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	thumbnail, err := encodeImage(fit(img, thumbnailDimension), "jpeg", thumbnailQuality)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+
+	processed := &processedImage{
+		Data:      encoded.data,
+		Width:     encoded.width,
+		Height:    encoded.height,
+		Thumbnail: thumbnail,
+		Hash:      imageHash(img),
+	}
+	if format == "png" {
+		processed.ContentType, processed.Ext = "image/png", ".png"
+	} else {
+		processed.ContentType, processed.Ext = "image/jpeg", ".jpg"
+	}
+	return processed, nil
+}
+
+type encodedImage struct {
+	data          []byte
+	width, height int
+}
+
+// encodeWithin encodes img as format, falling back to JPEG at lower quality
+// and then to smaller dimensions until the result fits in limit. It returns
+// the format that was used.
+func encodeWithin(img *image.RGBA, format string, limit int) (encodedImage, string, error) {
+	for {
+		bounds := img.Bounds()
+		if format == "png" {
+			data, err := encodeImage(img, "png", 0)
+			if err != nil {
+				return encodedImage{}, "", fmt.Errorf("failed to encode image: %w", err)
+			}
+			if len(data) <= limit {
+				return encodedImage{data, bounds.Dx(), bounds.Dy()}, "png", nil
+			}
+			format = "jpeg"
+		}
+
+		for _, quality := range []int{jpegQuality, 75, 65, 55} {
+			data, err := encodeImage(img, "jpeg", quality)
+			if err != nil {
+				return encodedImage{}, "", fmt.Errorf("failed to encode image: %w", err)
+			}
+			if len(data) <= limit {
+				return encodedImage{data, bounds.Dx(), bounds.Dy()}, "jpeg", nil
+			}
+		}
+
+		if bounds.Dx() <= thumbnailDimension && bounds.Dy() <= thumbnailDimension {
+			return encodedImage{}, "", &ImageError{Message: "Image cannot be compressed enough to send"}
+		}
+		img = scale(img, bounds.Dx()*3/4, bounds.Dy()*3/4)
+	}
+}
+
+func encodeImage(img *image.RGBA, format string, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if format == "png" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, flatten(img), &jpeg.Options{Quality: quality})
+	}
+	return buf.Bytes(), err
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
+
+// flatten puts transparent images on white, as JPEG has no alpha channel.
+func flatten(img *image.RGBA) *image.RGBA {
+	if img.Opaque() {
+		return img
+	}
+	flat := image.NewRGBA(img.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+	return flat
+}
+
+// fit scales img down, keeping its aspect ratio, so neither side is longer
+// than limit. Smaller images are returned as they are.
+func fit(img *image.RGBA, limit int) *image.RGBA {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	if width <= limit && height <= limit {
+		return img
+	}
+	if width >= height {
+		return scale(img, limit, max(1, height*limit/width))
+	}
+	return scale(img, max(1, width*limit/height), limit)
+}
+
+// scale resizes img to width by height, averaging the source pixels that
+// fall in each destination pixel. It is only used to shrink images.
+func scale(img *image.RGBA, width, height int) *image.RGBA {
+	srcWidth, srcHeight := img.Bounds().Dx(), img.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := y * srcHeight / height
+		y1 := max(y0+1, (y+1)*srcHeight/height)
+		for x := 0; x < width; x++ {
+			x0 := x * srcWidth / width
+			x1 := max(x0+1, (x+1)*srcWidth/width)
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := img.Pix[sy*img.Stride+x0*4 : sy*img.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+
+			n := (y1 - y0) * (x1 - x0)
+			offset := y*dst.Stride + x*4
+			for i := range sum {
+				dst.Pix[offset+i] = uint8(sum[i] / n)
+			}
+		}
+	}
+	return dst
+}
+
+// orient turns img upright according to an EXIF orientation value.
+func orient(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+
+	// source maps a destination pixel to the source pixel it shows.
+	source := map[int]func(x, y int) (int, int){
+		2: func(x, y int) (int, int) { return width - 1 - x, y },
+		3: func(x, y int) (int, int) { return width - 1 - x, height - 1 - y },
+		4: func(x, y int) (int, int) { return x, height - 1 - y },
+		5: func(x, y int) (int, int) { return y, x },
+		6: func(x, y int) (int, int) { return y, height - 1 - x },
+		7: func(x, y int) (int, int) { return width - 1 - y, height - 1 - x },
+		8: func(x, y int) (int, int) { return width - 1 - y, x },
+	}[orientation]
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		for x := 0; x < dstWidth; x++ {
+			sx, sy := source(x, y)
+			copy(dst.Pix[y*dst.Stride+x*4:y*dst.Stride+x*4+4], img.Pix[sy*img.Stride+sx*4:sy*img.Stride+sx*4+4])
+		}
+	}
+	return dst
+}
+
+// jpegOrientation reads the EXIF orientation of a JPEG, returning 1
+// (upright) when there is none.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xFF {
+			i++
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 {
+			// Metadata comes before the image data.
+			return 1
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[offset:]))
+	for n := 0; n < entries; n++ {
+		entry := offset + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 1
+}
@@ -0,0 +1,334 @@
+package content
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var (
+	red  = color.RGBA{R: 255, A: 255}
+	blue = color.RGBA{B: 255, A: 255}
+)
+
+// twoTone returns a width by height image, red on top and blue below.
+func twoTone(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		c := red
+		if y >= height/2 {
+			c = blue
+		}
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withOrientation inserts an EXIF segment carrying orientation straight
+// after the JPEG's start of image marker.
+func withOrientation(data []byte, order binary.ByteOrder, orientation int) []byte {
+	tiff := make([]byte, 26)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], 0x0112)
+	order.PutUint16(tiff[12:], 3)
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], uint16(orientation))
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+// withDimensions rewrites a PNG's header to claim width by height, leaving
+// the pixel data as it is.
+func withDimensions(data []byte, width, height int) []byte {
+	out := append([]byte{}, data...)
+	binary.BigEndian.PutUint32(out[16:], uint32(width))
+	binary.BigEndian.PutUint32(out[20:], uint32(height))
+	binary.BigEndian.PutUint32(out[29:], crc32.ChecksumIEEE(out[12:29]))
+	return out
+}
+
+func imageErrorMessage(err error) string {
+	var imageErr *ImageError
+	if errors.As(err, &imageErr) {
+		return imageErr.Message
+	}
+	return ""
+}
+
+func TestProcessImageRejects(t *testing.T) {
+	var gifData bytes.Buffer
+	if err := gif.Encode(&gifData, twoTone(8, 8), nil); err != nil {
+		t.Fatal(err)
+	}
+	small := encodePNG(t, twoTone(8, 8))
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{name: "gif", data: gifData.Bytes(), want: "GIF images are not supported. Please upload a JPEG or PNG"},
+		{name: "not an image", data: []byte("Memento mori."), want: "Unsupported image format (text/plain; charset=utf-8). Please upload a JPEG or PNG"},
+		{name: "damaged", data: small[:40], want: "Image could not be read, the file may be damaged"},
+		{name: "too many pixels", data: withDimensions(small, 10000, 10000), want: "Image dimensions too large (10000x10000)"},
+		{name: "file too large", data: append(small, make([]byte, maxUploadSize)...), want: "Image file too large (max 20MB)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := processImage(tt.data)
+			if got := imageErrorMessage(err); got != tt.want {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessImage(t *testing.T) {
+	tests := []struct {
+		name            string
+		data            []byte
+		wantContentType string
+		wantExt         string
+		wantWidth       int
+		wantHeight      int
+		wantThumbWidth  int
+		wantThumbHeight int
+	}{
+		{
+			name:            "jpeg",
+			data:            encodeJPEG(t, twoTone(40, 20)),
+			wantContentType: "image/jpeg", wantExt: ".jpg",
+			wantWidth: 40, wantHeight: 20, wantThumbWidth: 40, wantThumbHeight: 20,
+		},
+		{
+			name:            "png is kept as png",
+			data:            encodePNG(t, twoTone(40, 20)),
+			wantContentType: "image/png", wantExt: ".png",
+			wantWidth: 40, wantHeight: 20, wantThumbWidth: 40, wantThumbHeight: 20,
+		},
+		{
+			name:            "wide image is fitted",
+			data:            encodePNG(t, twoTone(3200, 800)),
+			wantContentType: "image/png", wantExt: ".png",
+			wantWidth: 1600, wantHeight: 400, wantThumbWidth: 320, wantThumbHeight: 80,
+		},
+		{
+			name:            "tall image is fitted",
+			data:            encodeJPEG(t, twoTone(500, 2000)),
+			wantContentType: "image/jpeg", wantExt: ".jpg",
+			wantWidth: 400, wantHeight: 1600, wantThumbWidth: 80, wantThumbHeight: 320,
+		},
+		{
+			name:            "rotated jpeg is turned upright",
+			data:            withOrientation(encodeJPEG(t, twoTone(40, 20)), binary.BigEndian, 6),
+			wantContentType: "image/jpeg", wantExt: ".jpg",
+			wantWidth: 20, wantHeight: 40, wantThumbWidth: 20, wantThumbHeight: 40,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processed, err := processImage(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if processed.ContentType != tt.wantContentType || processed.Ext != tt.wantExt {
+				t.Errorf("type = %s %s, want %s %s", processed.ContentType, processed.Ext, tt.wantContentType, tt.wantExt)
+			}
+
+			stored, _, err := image.Decode(bytes.NewReader(processed.Data))
+			if err != nil {
+				t.Fatalf("stored image does not decode: %v", err)
+			}
+			if got := stored.Bounds(); got.Dx() != tt.wantWidth || got.Dy() != tt.wantHeight {
+				t.Errorf("stored image is %dx%d, want %dx%d", got.Dx(), got.Dy(), tt.wantWidth, tt.wantHeight)
+			}
+			if processed.Width != tt.wantWidth || processed.Height != tt.wantHeight {
+				t.Errorf("Width, Height = %d, %d, want %d, %d", processed.Width, processed.Height, tt.wantWidth, tt.wantHeight)
+			}
+
+			thumbnail, err := jpeg.Decode(bytes.NewReader(processed.Thumbnail))
+			if err != nil {
+				t.Fatalf("thumbnail is not a JPEG: %v", err)
+			}
+			if got := thumbnail.Bounds(); got.Dx() != tt.wantThumbWidth || got.Dy() != tt.wantThumbHeight {
+				t.Errorf("thumbnail is %dx%d, want %dx%d", got.Dx(), got.Dy(), tt.wantThumbWidth, tt.wantThumbHeight)
+			}
+
+			if processed.Hash == "" {
+				t.Error("Hash is empty")
+			}
+		})
+	}
+}
+
+func TestProcessImageOrientation(t *testing.T) {
+	// Orientation 6 means the camera was turned a quarter clockwise, so the
+	// red top of the stored pixels ends up on the right.
+	data := withOrientation(encodeJPEG(t, twoTone(40, 20)), binary.LittleEndian, 6)
+	processed, err := processImage(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(processed.Data, []byte("Exif")) {
+		t.Error("stored image still has its EXIF segment")
+	}
+	if got := jpegOrientation(processed.Data); got != 1 {
+		t.Errorf("stored orientation = %d, want 1", got)
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(processed.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		x, y int
+		want color.RGBA
+	}{
+		{x: 3, y: 20, want: blue},
+		{x: 16, y: 20, want: red},
+	} {
+		r, g, b, _ := img.At(tt.x, tt.y).RGBA()
+		got := color.RGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: 255}
+		if !near(got, tt.want) {
+			t.Errorf("pixel (%d, %d) = %v, want about %v", tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func near(a, b color.RGBA) bool {
+	diff := func(x, y uint8) int {
+		if x > y {
+			return int(x - y)
+		}
+		return int(y - x)
+	}
+	return diff(a.R, b.R) < 40 && diff(a.G, b.G) < 40 && diff(a.B, b.B) < 40
+}
+
+func TestProcessImageOversized(t *testing.T) {
+	// Noise does not compress, so as a PNG this is well over the limit.
+	img := image.NewRGBA(image.Rect(0, 0, maxImageDimension, maxImageDimension))
+	rand.New(rand.NewSource(1)).Read(img.Pix)
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 255
+	}
+	data := encodePNG(t, img)
+	if len(data) <= maxImageSize {
+		t.Fatalf("fixture is %d bytes, want more than %d", len(data), maxImageSize)
+	}
+
+	processed, err := processImage(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(processed.Data) > maxImageSize {
+		t.Errorf("stored image is %d bytes, want at most %d", len(processed.Data), maxImageSize)
+	}
+	if processed.ContentType != "image/jpeg" || processed.Ext != ".jpg" {
+		t.Errorf("type = %s %s, want the PNG converted to JPEG", processed.ContentType, processed.Ext)
+	}
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	plain := encodeJPEG(t, twoTone(8, 8))
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{name: "no exif", data: plain, want: 1},
+		{name: "big endian", data: withOrientation(plain, binary.BigEndian, 6), want: 6},
+		{name: "little endian", data: withOrientation(plain, binary.LittleEndian, 8), want: 8},
+		{name: "not a jpeg", data: encodePNG(t, twoTone(8, 8)), want: 1},
+		{name: "truncated", data: withOrientation(plain, binary.BigEndian, 6)[:20], want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jpegOrientation(tt.data); got != tt.want {
+				t.Errorf("jpegOrientation = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownloadImageHash(t *testing.T) {
+	small := encodePNG(t, twoTone(8, 8))
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{name: "image", data: small},
+		{name: "too many pixels", data: withDimensions(small, 10000, 10000), wantErr: "image dimensions too large (10000x10000)"},
+		{name: "not an image", data: []byte("Memento mori."), wantErr: "image: unknown format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write(tt.data)
+			}))
+			defer server.Close()
+
+			hash, err := downloadImageHash(server.URL)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hash == "" {
+				t.Error("hash is empty")
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package content
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ImportRow is one row of a bulk import. Image names a file in the image
+// archive that goes with the rows.
+type ImportRow struct {
+	NewContent
+	Image string `json:"image"`
+}
+
+const (
+	ImportFormatCSV  = "csv"
+	ImportFormatJSON = "json"
+)
+
+// ImportFormatFor guesses the format of a rows file from its name.
+func ImportFormatFor(filename string) (string, error) {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv":
+		return ImportFormatCSV, nil
+	case ".json":
+		return ImportFormatJSON, nil
+	}
+	return "", fmt.Errorf("cannot tell the format of %q, expected .csv or .json", filename)
+}
+
+// ParseImportRows reads rows in format. CSV files need a header row naming
+// the columns, using the same names as the JSON fields.
+func ParseImportRows(r io.Reader, format string) ([]ImportRow, error) {
+	switch format {
+	case ImportFormatJSON:
+		var rows []ImportRow
+		if err := json.NewDecoder(r).Decode(&rows); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON rows: %w", err)
+		}
+		return rows, nil
+	case ImportFormatCSV:
+		return parseCSVRows(r)
+	}
+	return nil, fmt.Errorf("unknown import format %q", format)
+}
+
+func parseCSVRows(r io.Reader) ([]ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, required := range []string{"text_english", "theme"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header is missing the %s column", required)
+		}
+	}
+
+	var rows []ImportRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+		allowDuplicate := strings.TrimSpace(field("allow_duplicate"))
+		rows = append(rows, ImportRow{
+			NewContent: NewContent{
+				TextEnglish:    field("text_english"),
+				TextLatin:      field("text_latin"),
+				Theme:          field("theme"),
+				ImageSource:    field("image_source"),
+				TextSource:     field("text_source"),
+				AllowDuplicate: strings.EqualFold(allowDuplicate, "true") || allowDuplicate == "1",
+			},
+			Image: field("image"),
+		})
+	}
+	return rows, nil
+}
+
+// ImageArchive is a zip of the images referenced by import rows.
+type ImageArchive struct {
+	files map[string]*zip.File
+}
+
+func OpenImageArchive(r io.ReaderAt, size int64) (*ImageArchive, error) {
+	reader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image archive: %w", err)
+	}
+
+	archive := &ImageArchive{files: map[string]*zip.File{}}
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		archive.files[path.Clean(file.Name)] = file
+	}
+	return archive, nil
+}
+
+// image loads name from the archive. Its format is checked when it is
+// processed for upload.
+func (a *ImageArchive) image(name string) (*Image, error) {
+	if a == nil {
+		return nil, fmt.Errorf("image %q given but no image archive was uploaded", name)
+	}
+
+	file, ok := a.files[path.Clean(strings.TrimPrefix(name, "/"))]
+	if !ok {
+		return nil, fmt.Errorf("image %q is not in the archive", name)
+	}
+
+	if err := checkUploadSize(int64(file.UncompressedSize64)); err != nil {
+		return nil, err
+	}
+
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image %q: %w", name, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, maxUploadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image %q: %w", name, err)
+	}
+	if err := checkUploadSize(int64(len(data))); err != nil {
+		return nil, err
+	}
+
+	return &Image{Filename: path.Base(name), Data: data}, nil
+}
+
+const (
+	ImportCreated    = "created"
+	ImportFailed     = "failed"
+	ImportSkipped    = "skipped"
+	ImportRolledBack = "rolled_back"
+)
+
+// ImportResult is the outcome for one row. Row numbers start at 1.
+type ImportResult struct {
+	Row    int        `json:"row"`
+	Status string     `json:"status"`
+	ID     *uuid.UUID `json:"id,omitempty"`
+	Error  string     `json:"error,omitempty"`
+}
+
+type ImportReport struct {
+	Created    int            `json:"created"`
+	Failed     int            `json:"failed"`
+	RolledBack bool           `json:"rolled_back"`
+	Rows       []ImportResult `json:"rows"`
+}
+
+// Import validates every row and then creates content for the valid ones.
+// With allOrNothing, nothing is kept unless every row succeeds: invalid rows
+// stop the import before anything is written, and a failure part way
+// through deletes the content already created by this import.
+func (s *Service) Import(rows []ImportRow, archive *ImageArchive, allOrNothing bool) ImportReport {
+	report := ImportReport{Rows: make([]ImportResult, len(rows))}
+
+	images := make([]*Image, len(rows))
+	for i := range rows {
+		report.Rows[i] = ImportResult{Row: i + 1}
+		if err := s.prepareImportRow(&rows[i], archive, &images[i]); err != nil {
+			report.Rows[i].Status = ImportFailed
+			report.Rows[i].Error = err.Error()
+			report.Failed++
+		}
+	}
+
+	if allOrNothing && report.Failed > 0 {
+		for i := range report.Rows {
+			if report.Rows[i].Status == "" {
+				report.Rows[i].Status = ImportSkipped
+			}
+		}
+		return report
+	}
+
+	var created []int
+	for i, row := range rows {
+		if report.Rows[i].Status == ImportFailed {
+			continue
+		}
+
+		content, _, err := s.createContent(row.NewContent, images[i])
+		if err != nil {
+			report.Rows[i].Status = ImportFailed
+			report.Rows[i].Error = err.Error()
+			report.Failed++
+			if allOrNothing {
+				s.rollBackImport(&report, created, i)
+				return report
+			}
+			continue
+		}
+
+		report.Rows[i].Status = ImportCreated
+		report.Rows[i].ID = &content.ID
+		report.Created++
+		created = append(created, i)
+	}
+
+	log.Printf("Imported %d of %d content rows", report.Created, len(rows))
+	return report
+}
+
+func (s *Service) prepareImportRow(row *ImportRow, archive *ImageArchive, image **Image) error {
+	row.TextEnglish = strings.TrimSpace(row.TextEnglish)
+	row.TextLatin = strings.TrimSpace(row.TextLatin)
+	row.Theme = strings.TrimSpace(row.Theme)
+	row.ImageSource = strings.TrimSpace(row.ImageSource)
+	row.TextSource = strings.TrimSpace(row.TextSource)
+	row.Image = strings.TrimSpace(row.Image)
+
+	if err := row.Validate(); err != nil {
+		return err
+	}
+
+	if row.Image == "" {
+		return nil
+	}
+
+	loaded, err := archive.image(row.Image)
+	if err != nil {
+		return err
+	}
+	*image = loaded
+	return nil
+}
+
+// rollBackImport deletes the content created before row failed, and marks
+// the rows that were never attempted as skipped.
+func (s *Service) rollBackImport(report *ImportReport, created []int, failed int) {
+	for _, i := range created {
+		if err := s.DeleteContent(*report.Rows[i].ID); err != nil {
+			log.Printf("Failed to roll back imported content %s: %v", report.Rows[i].ID, err)
+			report.Rows[i].Error = "rollback failed: " + err.Error()
+			continue
+		}
+		report.Rows[i].Status = ImportRolledBack
+		report.Rows[i].ID = nil
+		report.Created--
+	}
+	for i := failed + 1; i < len(report.Rows); i++ {
+		if report.Rows[i].Status == "" {
+			report.Rows[i].Status = ImportSkipped
+		}
+	}
+	report.RolledBack = true
+}
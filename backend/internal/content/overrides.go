@@ -0,0 +1,232 @@
+package content
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"novissima/internal/users"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrOverrideNotFound = errors.New("schedule override not found")
+	ErrOverrideExists   = errors.New("an override already exists for that date and language")
+)
+
+const overrideDateFormat = "2006-01-02"
+
+// ScheduleOverride pins content to a date, taking precedence over the
+// rotation. A nil Language applies to every subscriber; otherwise only to
+// subscribers with that language setting.
+type ScheduleOverride struct {
+	ID        uuid.UUID `json:"id"`
+	Date      string    `json:"date"`
+	Language  *string   `json:"language"`
+	ContentID uuid.UUID `json:"content_id"`
+	Note      *string   `json:"note"`
+	CreatedBy *string   `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type OverrideCreate struct {
+	Date      string    `json:"date"`
+	Language  *string   `json:"language"`
+	ContentID uuid.UUID `json:"content_id"`
+	Note      *string   `json:"note"`
+	CreatedBy *string   `json:"created_by"`
+}
+
+// OverrideFilter selects overrides between two YYYY-MM-DD dates, both
+// inclusive. Empty bounds are open.
+type OverrideFilter struct {
+	From string
+	To   string
+}
+
+type OverrideStore interface {
+	InsertOverride(override OverrideCreate) (ScheduleOverride, error)
+	// ListOverrides returns the overrides matching filter ordered by date.
+	ListOverrides(filter OverrideFilter) ([]ScheduleOverride, error)
+	DeleteOverride(id uuid.UUID) error
+}
+
+// Validate returns an error meant to be shown to the client.
+func (o OverrideCreate) Validate() error {
+	if _, err := time.Parse(overrideDateFormat, o.Date); err != nil {
+		return errors.New("date must be a YYYY-MM-DD date")
+	}
+	if o.Language != nil && !users.IsValidLanguage(*o.Language) {
+		return errors.New("language must be en, la or both, or omitted for every language")
+	}
+	if o.ContentID == uuid.Nil {
+		return errors.New("content_id is required")
+	}
+	return nil
+}
+
+// AddOverride pins content to a date. The returned warnings point out
+// subscribers the pinned item cannot fully serve, such as Latin readers when
+// it has no Latin text.
+func (s *Service) AddOverride(create OverrideCreate) (ScheduleOverride, []string, error) {
+	content, err := s.store.GetContent(create.ContentID)
+	if err != nil {
+		return ScheduleOverride{}, nil, fmt.Errorf("failed to get pinned content: %w", err)
+	}
+
+	existing, err := s.overrides.ListOverrides(OverrideFilter{From: create.Date, To: create.Date})
+	if err != nil {
+		return ScheduleOverride{}, nil, fmt.Errorf("failed to check existing overrides: %w", err)
+	}
+	for _, override := range existing {
+		if valueOf(override.Language) == valueOf(create.Language) {
+			return ScheduleOverride{}, nil, ErrOverrideExists
+		}
+	}
+
+	created, err := s.overrides.InsertOverride(create)
+	if err != nil {
+		return ScheduleOverride{}, nil, fmt.Errorf("failed to add override: %w", err)
+	}
+
+	var warnings []string
+	if content.Status != StatusApproved {
+		warnings = append(warnings, fmt.Sprintf("content %s is %s and will not be sent unless it is approved by then", content.ID, content.Status))
+	}
+	for _, language := range overrideLanguages(create.Language) {
+		if warning := translationWarning(content, language); warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+
+	log.Printf("Pinned content %s to %s", created.ContentID, created.Date)
+	return created, warnings, nil
+}
+
+func (s *Service) ListOverrides(filter OverrideFilter) ([]ScheduleOverride, error) {
+	overrides, err := s.overrides.ListOverrides(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list overrides: %w", err)
+	}
+	return overrides, nil
+}
+
+func (s *Service) DeleteOverride(id uuid.UUID) error {
+	if err := s.overrides.DeleteOverride(id); err != nil {
+		return fmt.Errorf("failed to delete override: %w", err)
+	}
+	return nil
+}
+
+// GetDailyContentFor returns the approved item for subscribers with
+// language on date. An override for that language wins, then one for every language,
+// and otherwise the rotation picks from the items not pinned that day. An
+// override whose item lacks the Latin text Latin readers need is passed over
+// for them with a warning, so they still get something they can read.
+// Themes, when given, are the themes the subscribers follow: overrides still
+// apply, but the rotation only picks from those themes.
+func (s *Service) GetDailyContentFor(date time.Time, language string, themes []string) (*Content, error) {
+	key := calendarDate(date).Format(overrideDateFormat)
+	overrides, err := s.overrides.ListOverrides(OverrideFilter{From: key, To: key})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get overrides: %w", err)
+	}
+
+	for _, override := range applicableOverrides(overrides, language) {
+		content, err := s.store.GetContent(override.ContentID)
+		if errors.Is(err, ErrContentNotFound) {
+			log.Printf("Content %s pinned to %s no longer exists, ignoring the override", override.ContentID, key)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pinned content: %w", err)
+		}
+		if content.Status != StatusApproved {
+			warning := fmt.Sprintf("content %s is %s, not approved, so it is not sent", content.ID, content.Status)
+			log.Printf("Override %s on %s: %s", override.ID, key, warning)
+			s.loggingService.LogOverrideWarning(override.ID, content.ID, key, language, warning)
+			continue
+		}
+
+		if warning := translationWarning(content, language); warning != "" {
+			log.Printf("Override %s on %s: %s", override.ID, key, warning)
+			s.loggingService.LogOverrideWarning(override.ID, content.ID, key, language, warning)
+			if language == "la" {
+				continue
+			}
+		}
+		return &content, nil
+	}
+
+	contents, err := s.store.ListContent(ContentFilter{Status: StatusApproved})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily content: %w", err)
+	}
+
+	content, err := s.rotation.selectContent(withoutPinned(contents, overrides), date, themes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select daily content: %w", err)
+	}
+
+	return content, nil
+}
+
+// applicableOverrides returns the overrides for language followed by the
+// ones for every language.
+func applicableOverrides(overrides []ScheduleOverride, language string) []ScheduleOverride {
+	var specific, general []ScheduleOverride
+	for _, override := range overrides {
+		switch {
+		case override.Language == nil:
+			general = append(general, override)
+		case *override.Language == language:
+			specific = append(specific, override)
+		}
+	}
+	return append(specific, general...)
+}
+
+// withoutPinned drops items pinned that day, so a language-scoped pin is not
+// also picked, or reused, by the rotation for everyone else.
+func withoutPinned(contents []Content, overrides []ScheduleOverride) []Content {
+	if len(overrides) == 0 {
+		return contents
+	}
+
+	pinned := map[uuid.UUID]bool{}
+	for _, override := range overrides {
+		pinned[override.ContentID] = true
+	}
+
+	var remaining []Content
+	for _, content := range contents {
+		if !pinned[content.ID] {
+			remaining = append(remaining, content)
+		}
+	}
+	return remaining
+}
+
+func overrideLanguages(language *string) []string {
+	if language == nil {
+		return []string{"la", "both"}
+	}
+	return []string{*language}
+}
+
+// translationWarning describes what subscribers with language miss out on
+// when sent content, or returns "" if nothing is missing.
+func translationWarning(content Content, language string) string {
+	if valueOf(content.TextLatin) != "" {
+		return ""
+	}
+	switch language {
+	case "la":
+		return fmt.Sprintf("content %s has no Latin text, Latin subscribers cannot receive it", content.ID)
+	case "both":
+		return fmt.Sprintf("content %s has no Latin text, subscribers reading both languages will only get English", content.ID)
+	}
+	return ""
+}
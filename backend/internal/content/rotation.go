@@ -0,0 +1,200 @@
+package content
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"time"
+
+	"novissima/internal/liturgical"
+)
+
+// RotationPolicy decides which item is sent on a given day. Themes are
+// cycled one per day starting at StartDate; within a theme the least
+// recently sent item wins, and an item is not repeated until MinRepeatGap
+// has passed since it was last sent. When Calendar has a rule for the day's
+// feast or season, its pinned item or theme is used first.
+type RotationPolicy struct {
+	Themes       []string
+	StartDate    time.Time
+	MinRepeatGap time.Duration
+	Calendar     *liturgical.Rules
+}
+
+// themesFor returns the policy's themes starting with the one scheduled for
+// date, followed by the rest of the cycle in order. The later themes are
+// the fallbacks used when the scheduled theme is exhausted.
+func (p RotationPolicy) themesFor(date time.Time) []string {
+	return cycleFrom(p.Themes, daysBetween(p.StartDate, date))
+}
+
+// followedThemesFor orders the followed themes that have content the way
+// the policy orders its own, unknown themes last, and cycles through them
+// one per day like themesFor.
+func (p RotationPolicy) followedThemesFor(date time.Time, follows []string, contents []Content) []string {
+	position := map[string]int{}
+	for i, theme := range p.Themes {
+		if _, ok := position[theme]; !ok {
+			position[theme] = i
+		}
+	}
+	hasContent := map[string]bool{}
+	for _, c := range contents {
+		hasContent[c.Theme] = true
+	}
+
+	var themes []string
+	for _, theme := range follows {
+		if hasContent[theme] && !slices.Contains(themes, theme) {
+			themes = append(themes, theme)
+		}
+	}
+	sort.SliceStable(themes, func(i, j int) bool {
+		a, aKnown := position[themes[i]]
+		b, bKnown := position[themes[j]]
+		if aKnown != bKnown {
+			return aKnown
+		}
+		if aKnown && a != b {
+			return a < b
+		}
+		return themes[i] < themes[j]
+	})
+	return cycleFrom(themes, daysBetween(p.StartDate, date))
+}
+
+// cycleFrom returns themes rotated to start at index day, wrapping around.
+func cycleFrom(themes []string, day int) []string {
+	if len(themes) == 0 {
+		return nil
+	}
+
+	start := day % len(themes)
+	if start < 0 {
+		start += len(themes)
+	}
+
+	ordered := make([]string, 0, len(themes))
+	for i := range themes {
+		ordered = append(ordered, themes[(start+i)%len(themes)])
+	}
+	return ordered
+}
+
+// selectContent picks the item for date out of contents, from the followed
+// themes if there are any with content and otherwise from the policy's. The
+// choice only depends on the contents and the date, so running it again for
+// the same day returns the same item: an item of a theme that was already
+// sent for that date is reused. Reuse is per theme, so items sent to users
+// following other themes do not change what everyone else gets.
+func (p RotationPolicy) selectContent(contents []Content, date time.Time, follows []string) (*Content, error) {
+	if len(contents) == 0 {
+		return nil, fmt.Errorf("no content available")
+	}
+
+	sorted := make([]Content, len(contents))
+	copy(sorted, contents)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return lessRecentlySent(sorted[i], sorted[j])
+	})
+
+	themes := p.themesFor(date)
+	following := false
+	if len(follows) > 0 {
+		if followed := p.followedThemesFor(date, follows, sorted); len(followed) > 0 {
+			themes, following = followed, true
+		} else {
+			log.Printf("No content in followed themes %v, using the rotation instead", follows)
+		}
+	}
+
+	// Calendar pins apply to everyone, like overrides.
+	if rule, label, ok := p.Calendar.RuleFor(date); ok {
+		if rule.ContentID != nil {
+			for i := range sorted {
+				if sorted[i].ID == *rule.ContentID {
+					return &sorted[i], nil
+				}
+			}
+			log.Printf("Content %s pinned for %s not found, using the rotation instead", rule.ContentID, label)
+		}
+		if rule.Theme != "" && (!following || slices.Contains(themes, rule.Theme)) {
+			themes = withThemeFirst(themes, rule.Theme)
+		}
+	}
+
+	day := calendarDate(date)
+	cutoff := day.Add(-p.MinRepeatGap)
+	for _, theme := range themes {
+		for i := range sorted {
+			c := &sorted[i]
+			if c.Theme == theme && c.LastSent != nil && calendarDate(c.LastSent.UTC()).Equal(day) {
+				return c, nil
+			}
+		}
+		for i := range sorted {
+			c := &sorted[i]
+			if c.Theme != theme {
+				continue
+			}
+			if c.LastSent == nil || !c.LastSent.After(cutoff) {
+				if theme != themes[0] {
+					log.Printf("Theme %s is exhausted, falling back to %s", themes[0], theme)
+				}
+				return c, nil
+			}
+		}
+	}
+
+	// Every theme is inside the repeat gap, so repeat the least recently
+	// sent item rather than send nothing.
+	for _, theme := range themes {
+		for i := range sorted {
+			if sorted[i].Theme == theme {
+				log.Printf("All themes exhausted, repeating content %s", sorted[i].ID)
+				return &sorted[i], nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no content available for themes: %v", themes)
+}
+
+func withThemeFirst(themes []string, first string) []string {
+	ordered := []string{first}
+	for _, theme := range themes {
+		if theme != first {
+			ordered = append(ordered, theme)
+		}
+	}
+	return ordered
+}
+
+// lessRecentlySent orders never-sent items first, then by last_sent, with
+// created_at and ID as tie-breakers so the order is stable across reruns.
+func lessRecentlySent(a, b Content) bool {
+	switch {
+	case a.LastSent == nil && b.LastSent != nil:
+		return true
+	case a.LastSent != nil && b.LastSent == nil:
+		return false
+	case a.LastSent != nil && b.LastSent != nil && !a.LastSent.Equal(*b.LastSent):
+		return a.LastSent.Before(*b.LastSent)
+	case !a.CreatedAt.Equal(b.CreatedAt):
+		return a.CreatedAt.Before(b.CreatedAt)
+	default:
+		return a.ID.String() < b.ID.String()
+	}
+}
+
+// calendarDate returns midnight UTC of t's calendar date in t's own
+// location. Users in different time zones reach the same date at different
+// instants, so dates rather than instants identify a day's content.
+func calendarDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func daysBetween(start, date time.Time) int {
+	return int(calendarDate(date).Sub(calendarDate(start)).Hours() / 24)
+}
@@ -0,0 +1,195 @@
+package content
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"novissima/internal/liturgical"
+)
+
+func TestSelectContent(t *testing.T) {
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	// The day after All Souls, so the All Souls rules below do not apply.
+	day := time.Date(2026, 11, 3, 7, 0, 0, 0, time.UTC)
+	allSouls := time.Date(2026, 11, 2, 7, 0, 0, 0, time.UTC)
+	sentAt := func(offset time.Duration) *time.Time {
+		at := day.Add(offset)
+		return &at
+	}
+	item := func(id int, theme string, lastSent *time.Time) Content {
+		return Content{
+			ID:        uuid.MustParse(fmt.Sprintf("00000000-0000-0000-0000-%012d", id)),
+			Theme:     theme,
+			LastSent:  lastSent,
+			CreatedAt: start,
+		}
+	}
+
+	// Day 33 of a two-theme cycle, so "judgment" is scheduled on day.
+	policy := RotationPolicy{
+		Themes:       []string{"death", "judgment"},
+		StartDate:    start,
+		MinRepeatGap: 30 * 24 * time.Hour,
+	}
+	pinned := item(9, "heaven", sentAt(-24*time.Hour))
+
+	tests := []struct {
+		name     string
+		policy   RotationPolicy
+		date     time.Time
+		contents []Content
+		follows  []string
+		wantID   int
+	}{
+		{
+			name:   "scheduled theme",
+			policy: policy,
+			date:   day,
+			contents: []Content{
+				item(1, "death", nil),
+				item(2, "judgment", nil),
+			},
+			wantID: 2,
+		},
+		{
+			name:   "next day moves to the next theme",
+			policy: policy,
+			date:   day.AddDate(0, 0, 1),
+			contents: []Content{
+				item(1, "death", nil),
+				item(2, "judgment", nil),
+			},
+			wantID: 1,
+		},
+		{
+			name:   "least recently sent wins",
+			policy: policy,
+			date:   day,
+			contents: []Content{
+				item(1, "judgment", sentAt(-40*24*time.Hour)),
+				item(2, "judgment", sentAt(-60*24*time.Hour)),
+			},
+			wantID: 2,
+		},
+		{
+			name:   "never sent comes first",
+			policy: policy,
+			date:   day,
+			contents: []Content{
+				item(1, "judgment", sentAt(-60*24*time.Hour)),
+				item(2, "judgment", nil),
+			},
+			wantID: 2,
+		},
+		{
+			name:   "theme inside the repeat gap falls back",
+			policy: policy,
+			date:   day,
+			contents: []Content{
+				item(1, "judgment", sentAt(-5*24*time.Hour)),
+				item(2, "death", sentAt(-50*24*time.Hour)),
+			},
+			wantID: 2,
+		},
+		{
+			name:   "everything inside the gap repeats the oldest",
+			policy: policy,
+			date:   day,
+			contents: []Content{
+				item(1, "judgment", sentAt(-5*24*time.Hour)),
+				item(2, "judgment", sentAt(-10*24*time.Hour)),
+				item(3, "death", sentAt(-20*24*time.Hour)),
+			},
+			wantID: 2,
+		},
+		{
+			name:   "item already sent today is reused",
+			policy: policy,
+			date:   day,
+			contents: []Content{
+				item(1, "judgment", sentAt(-time.Hour)),
+				item(2, "judgment", nil),
+			},
+			wantID: 1,
+		},
+		{
+			name:   "followed themes replace the rotation",
+			policy: policy,
+			date:   day,
+			contents: []Content{
+				item(1, "death", nil),
+				item(2, "judgment", nil),
+				item(3, "hell", nil),
+			},
+			follows: []string{"hell"},
+			wantID:  3,
+		},
+		{
+			name:   "followed themes without content use the rotation",
+			policy: policy,
+			date:   day,
+			contents: []Content{
+				item(1, "death", nil),
+				item(2, "judgment", nil),
+			},
+			follows: []string{"hell"},
+			wantID:  2,
+		},
+		{
+			name: "calendar theme",
+			policy: RotationPolicy{
+				Themes:       policy.Themes,
+				StartDate:    start,
+				MinRepeatGap: policy.MinRepeatGap,
+				Calendar: &liturgical.Rules{Feasts: map[string]liturgical.Rule{
+					liturgical.AllSouls: {Theme: "purgatory"},
+				}},
+			},
+			date: allSouls,
+			contents: []Content{
+				item(1, "death", nil),
+				item(2, "judgment", nil),
+				item(3, "purgatory", nil),
+			},
+			wantID: 3,
+		},
+		{
+			name: "calendar pin ignores the repeat gap",
+			policy: RotationPolicy{
+				Themes:       policy.Themes,
+				StartDate:    start,
+				MinRepeatGap: policy.MinRepeatGap,
+				Calendar: &liturgical.Rules{Feasts: map[string]liturgical.Rule{
+					liturgical.AllSouls: {ContentID: &pinned.ID},
+				}},
+			},
+			date: allSouls,
+			contents: []Content{
+				item(1, "death", nil),
+				pinned,
+			},
+			wantID: 9,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.policy.selectContent(tt.contents, tt.date, tt.follows)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := item(tt.wantID, "", nil).ID; got.ID != want {
+				t.Errorf("selected %s, want %s", got.ID, want)
+			}
+		})
+	}
+}
+
+func TestSelectContentEmpty(t *testing.T) {
+	policy := RotationPolicy{Themes: []string{"death"}}
+	if _, err := policy.selectContent(nil, time.Now(), nil); err == nil {
+		t.Error("selectContent with no content succeeded, want an error")
+	}
+}
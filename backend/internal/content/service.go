@@ -1,20 +1,19 @@
 package content
 
 import (
-	"bytes"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"mime/multipart"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
 	"novissima/internal/logging"
 
 	"github.com/google/uuid"
-	storage_go "github.com/supabase-community/storage-go"
-	"github.com/supabase-community/supabase-go"
 )
 
 type Content struct {
@@ -26,8 +25,14 @@ type Content struct {
 	Theme       string     `json:"theme"`
 	ImageSource *string    `json:"image_source"`
 	TextSource  *string    `json:"text_source"`
-	UpdatedAt   time.Time  `json:"updated_at"`
-	CreatedAt   time.Time  `json:"created_at"`
+	Status      string     `json:"status"`
+	// ReviewComment is the reason given when the content was last rejected.
+	ReviewComment *string   `json:"review_comment"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	CreatedAt     time.Time `json:"created_at"`
+
+	ImageInfo
+	ContentHashes
 }
 
 type ContentCreate struct {
@@ -37,142 +42,417 @@ type ContentCreate struct {
 	Theme       string  `json:"theme"`
 	ImageSource *string `json:"image_source"`
 	TextSource  *string `json:"text_source"`
+
+	ImageInfo
+	ContentHashes
 }
 
 type Service struct {
-	dbClient       *supabase.Client
+	store          ContentStore
+	overrides      OverrideStore
+	images         ImageStore
 	loggingService *logging.Service
-	bucketName     string
+	rotation       RotationPolicy
 }
 
-func NewService(dbClient *supabase.Client, loggingService *logging.Service, bucketName string) *Service {
+func NewService(store ContentStore, overrides OverrideStore, images ImageStore, loggingService *logging.Service, rotation RotationPolicy) *Service {
 	return &Service{
-		dbClient:       dbClient,
+		store:          store,
+		overrides:      overrides,
+		images:         images,
 		loggingService: loggingService,
-		bucketName:     bucketName,
+		rotation:       rotation,
 	}
 }
 
-func (s *Service) AddContent(contentEnglish string, contentLatin string, file multipart.File, header *multipart.FileHeader, theme string, imageSource string, textSource string) (Content, error) {
+// ContentChanges describes an edit to existing content. Nil fields are left
+// unchanged; a pointer to "" clears an optional field.
+type ContentChanges struct {
+	TextEnglish *string
+	TextLatin   *string
+	Theme       *string
+	ImageSource *string
+	TextSource  *string
+	RemoveImage bool
+}
 
-	var imageURL string
+// NewContent is new content as entered by an editor, before optional fields
+// are normalized and the image is uploaded.
+type NewContent struct {
+	TextEnglish string `json:"text_english"`
+	TextLatin   string `json:"text_latin"`
+	Theme       string `json:"theme"`
+	ImageSource string `json:"image_source"`
+	TextSource  string `json:"text_source"`
+	// AllowDuplicate creates the content even if it duplicates existing
+	// content, which is then only reported.
+	AllowDuplicate bool `json:"allow_duplicate"`
+}
 
-	if file != nil && header != nil {
-		defer file.Close()
+// Validate applies the same rules as the upload form. The error is meant to
+// be shown to the editor.
+func (c NewContent) Validate() error {
+	if strings.TrimSpace(c.TextEnglish) == "" {
+		return errors.New("textEnglish is required")
+	}
+	if strings.TrimSpace(c.Theme) == "" {
+		return errors.New("theme is required")
+	}
+	return nil
+}
 
-		fileExt := ""
-		if idx := strings.LastIndex(header.Filename, "."); idx != -1 {
-			fileExt = header.Filename[idx:]
-		}
-		filename := fmt.Sprintf("%s/%s%s", theme, uuid.New().String(), fileExt)
+// Image is an uploaded image file before it has been checked and processed.
+type Image struct {
+	Filename string
+	Data     []byte
+}
 
-		fileBytes, err := io.ReadAll(file)
+// AddContent creates content from the upload form. Content duplicating an
+// existing text or image is rejected with a *DuplicateError unless
+// allowDuplicate is set, in which case the duplicates are returned.
+func (s *Service) AddContent(contentEnglish string, contentLatin string, file multipart.File, header *multipart.FileHeader, theme string, imageSource string, textSource string, allowDuplicate bool) (Content, []DuplicateMatch, error) {
+
+	var image *Image
+
+	if file != nil && header != nil {
+		var err error
+		image, err = readImage(file, header)
 		if err != nil {
-			return Content{}, fmt.Errorf("failed to read file: %w", err)
+			return Content{}, nil, err
 		}
+	}
 
-		fileReader := bytes.NewReader(fileBytes)
-
-		contentType := header.Header.Get("Content-Type")
-		if contentType == "" {
-			switch fileExt {
-			case ".jpg", ".jpeg":
-				contentType = "image/jpeg"
-			case ".png":
-				contentType = "image/png"
-			case ".gif":
-				contentType = "image/gif"
-			default:
-				contentType = "application/octet-stream"
-			}
-		}
+	return s.createContent(NewContent{
+		TextEnglish:    contentEnglish,
+		TextLatin:      contentLatin,
+		Theme:          theme,
+		ImageSource:    imageSource,
+		TextSource:     textSource,
+		AllowDuplicate: allowDuplicate,
+	}, image)
+}
 
-		fileOptions := storage_go.FileOptions{
-			ContentType: &contentType,
+// createContent checks for duplicates, uploads image, if any, and inserts
+// the content row.
+func (s *Service) createContent(newContent NewContent, image *Image) (Content, []DuplicateMatch, error) {
+	var processed *processedImage
+	if image != nil {
+		var err error
+		processed, err = processImage(image.Data)
+		if err != nil {
+			return Content{}, nil, err
 		}
-		_, err = s.dbClient.Storage.UploadFile(s.bucketName, filename, fileReader, fileOptions)
+	}
+
+	hashes := ContentHashes{
+		EnglishHash: textHash(newContent.TextEnglish, false),
+		LatinHash:   textHash(newContent.TextLatin, true),
+	}
+	if processed != nil {
+		hashes.ImageHash = &processed.Hash
+	}
+
+	duplicates, err := s.findDuplicates(hashes)
+	if err != nil {
+		return Content{}, nil, err
+	}
+	if len(duplicates) > 0 && !newContent.AllowDuplicate {
+		return Content{}, nil, &DuplicateError{Matches: duplicates}
+	}
+
+	var imageURL string
+	var info ImageInfo
+	if processed != nil {
+		imageURL, info, err = s.uploadImage(processed, newContent.Theme)
 		if err != nil {
-			return Content{}, fmt.Errorf("failed to upload to storage: %w", err)
+			return Content{}, nil, err
 		}
-		imageURL = s.dbClient.Storage.GetPublicUrl(s.bucketName, filename).SignedURL
 	}
 
 	content := ContentCreate{
-		TextEnglish: contentEnglish,
-		TextLatin:   &contentLatin,
-		ImageURL:    &imageURL,
-		Theme:       theme,
-		ImageSource: &imageSource,
-		TextSource:  &textSource,
+		TextEnglish:   newContent.TextEnglish,
+		TextLatin:     optional(newContent.TextLatin),
+		ImageURL:      optional(imageURL),
+		Theme:         newContent.Theme,
+		ImageSource:   optional(newContent.ImageSource),
+		TextSource:    optional(newContent.TextSource),
+		ImageInfo:     info,
+		ContentHashes: hashes,
 	}
 
-	data, _, err := s.dbClient.From("content").Insert(content, true, "", "", "").Execute()
+	created, err := s.store.InsertContent(content)
 	if err != nil {
-		log.Printf("Supabase error: %v", err)
-		if data != nil {
-			log.Printf("Supabase response: %s", string(data))
+		log.Printf("Store error: %v", err)
+		if imageURL != "" {
+			s.deleteImages(imageURL, info.ThumbnailURL)
 		}
-		return Content{}, fmt.Errorf("failed to add content: %w", err)
+		return Content{}, nil, fmt.Errorf("failed to add content: %w", err)
 	}
 
-	log.Printf("Successfully added content: %s", data)
+	log.Printf("Successfully added content: %s", created.ID)
+	if len(duplicates) > 0 {
+		log.Printf("Content %s was added although it is a %v", created.ID, &DuplicateError{Matches: duplicates})
+	}
+	s.loggingService.LogContentCreated(created.ID, newContent.TextEnglish, newContent.TextLatin, imageURL, newContent.Theme, newContent.ImageSource, newContent.TextSource)
 
-	return Content{}, nil
+	return created, duplicates, nil
 }
 
-func (s *Service) GetDailyContent() (*Content, error) {
-	themes := []string{"death"}
-	startDate := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+func readImage(file multipart.File, header *multipart.FileHeader) (*Image, error) {
+	fileBytes, err := io.ReadAll(io.LimitReader(file, maxUploadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
 
-	now := time.Now()
+	return &Image{Filename: header.Filename, Data: fileBytes}, nil
+}
 
-	cycleDay := int(now.Sub(startDate).Hours()/24) % len(themes)
+// uploadImage stores a processed image along with its thumbnail, returning
+// the image's URL and what is known about it.
+func (s *Service) uploadImage(processed *processedImage, theme string) (string, ImageInfo, error) {
+	name := fmt.Sprintf("%s/%s", theme, uuid.New().String())
+	imageURL, err := s.images.Upload(name+processed.Ext, processed.Data, processed.ContentType)
+	if err != nil {
+		return "", ImageInfo{}, fmt.Errorf("failed to upload to storage: %w", err)
+	}
 
-	currentTheme := themes[cycleDay]
+	thumbnailURL, err := s.images.Upload(name+"-thumb.jpg", processed.Thumbnail, "image/jpeg")
+	if err != nil {
+		s.deleteImage(imageURL)
+		return "", ImageInfo{}, fmt.Errorf("failed to upload thumbnail to storage: %w", err)
+	}
 
-	// thirtyDaysAgo := now.AddDate(0, 0, -30)
+	size := int64(len(processed.Data))
+	return imageURL, ImageInfo{
+		ThumbnailURL: &thumbnailURL,
+		ImageWidth:   &processed.Width,
+		ImageHeight:  &processed.Height,
+		ImageSize:    &size,
+	}, nil
+}
 
-	content, _, err := s.dbClient.From("content").
-		Select("*", "", false).
-		Eq("theme", currentTheme).
-		Limit(1, "").
-		Execute()
+// ListContent returns one page of content matching filter along with the
+// total number of matches.
+func (s *Service) ListContent(filter ContentFilter) ([]Content, int, error) {
+	contents, err := s.store.ListContent(filter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get daily content: %w", err)
+		return nil, 0, fmt.Errorf("failed to list content: %w", err)
 	}
 
-	var contents []Content
-	if err := json.Unmarshal(content, &contents); err != nil {
-		return nil, fmt.Errorf("failed to parse content: %w", err)
+	total, err := s.store.CountContent(filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count content: %w", err)
 	}
 
-	if len(contents) == 0 {
-		return nil, fmt.Errorf("no content available for today's theme: %s", currentTheme)
+	return contents, total, nil
+}
+
+// Themes returns the themes of approved content, which are the ones users
+// can follow.
+func (s *Service) Themes() ([]string, error) {
+	contents, err := s.store.ListContent(ContentFilter{Status: StatusApproved})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list themes: %w", err)
 	}
 
-	return &contents[0], nil
+	var themes []string
+	for _, c := range contents {
+		if !slices.Contains(themes, c.Theme) {
+			themes = append(themes, c.Theme)
+		}
+	}
+	sort.Strings(themes)
+	return themes, nil
 }
 
-func (s *Service) UpdateLastSent(id uuid.UUID) error {
-	_, _, err := s.dbClient.From("content").Update(map[string]interface{}{
-		"last_sent": time.Now(),
-	}, "", "").Eq("id", id.String()).Execute()
-	return err
+func (s *Service) GetContent(id uuid.UUID) (Content, error) {
+	content, err := s.store.GetContent(id)
+	if err != nil {
+		return Content{}, fmt.Errorf("failed to get content: %w", err)
+	}
+	return content, nil
 }
 
-func isValidImageType(contentType string) bool {
-	validTypes := []string{
-		"image/jpeg",
-		"image/jpg",
-		"image/png",
-		"image/gif",
+// UpdateContent applies changes to the content with id on behalf of actor.
+// A new image replaces the current one, and the old file is removed from
+// storage once the row has been saved. Changing the text, theme or image of
+// approved content sends it back to review in the same write, so edits are
+// never broadcast unreviewed, and an edit that fails leaves the content as
+// it was.
+func (s *Service) UpdateContent(id uuid.UUID, changes ContentChanges, file multipart.File, header *multipart.FileHeader, actor string) (Content, error) {
+	existing, err := s.store.GetContent(id)
+	if err != nil {
+		return Content{}, fmt.Errorf("failed to get content: %w", err)
+	}
+
+	status := existing.Status
+	revise := existing.Status == StatusApproved && changesReviewedFields(existing, changes, file != nil && header != nil)
+	if revise {
+		status = transitions[ActionRevise].to
 	}
 
-	for _, validType := range validTypes {
-		if contentType == validType {
-			return true
+	update := ContentCreate{
+		TextEnglish: existing.TextEnglish,
+		TextLatin:   existing.TextLatin,
+		ImageURL:    existing.ImageURL,
+		Theme:       existing.Theme,
+		ImageSource: existing.ImageSource,
+		TextSource:  existing.TextSource,
+		ImageInfo:   existing.ImageInfo,
+		ContentHashes: ContentHashes{
+			ImageHash: existing.ImageHash,
+		},
+	}
+
+	var fields []string
+	if changes.TextEnglish != nil {
+		update.TextEnglish = *changes.TextEnglish
+		fields = append(fields, "text_english")
+	}
+	if changes.TextLatin != nil {
+		update.TextLatin = optional(*changes.TextLatin)
+		fields = append(fields, "text_latin")
+	}
+	if changes.Theme != nil {
+		update.Theme = *changes.Theme
+		fields = append(fields, "theme")
+	}
+	if changes.ImageSource != nil {
+		update.ImageSource = optional(*changes.ImageSource)
+		fields = append(fields, "image_source")
+	}
+	if changes.TextSource != nil {
+		update.TextSource = optional(*changes.TextSource)
+		fields = append(fields, "text_source")
+	}
+
+	if file != nil && header != nil {
+		image, err := readImage(file, header)
+		if err != nil {
+			return Content{}, err
 		}
+		processed, err := processImage(image.Data)
+		if err != nil {
+			return Content{}, err
+		}
+		imageURL, info, err := s.uploadImage(processed, update.Theme)
+		if err != nil {
+			return Content{}, err
+		}
+		update.ImageURL = &imageURL
+		update.ImageInfo = info
+		update.ImageHash = &processed.Hash
+		fields = append(fields, "image_url")
+	} else if changes.RemoveImage {
+		update.ImageURL = nil
+		update.ImageInfo = ImageInfo{}
+		update.ImageHash = nil
+		fields = append(fields, "image_url")
+	}
+	update.EnglishHash = textHash(update.TextEnglish, false)
+	update.LatinHash = textHash(valueOf(update.TextLatin), true)
+
+	updated, err := s.store.UpdateContent(id, update, existing.Status, status)
+	if err != nil {
+		if valueOf(update.ImageURL) != valueOf(existing.ImageURL) && update.ImageURL != nil {
+			s.deleteImages(*update.ImageURL, update.ThumbnailURL)
+		}
+		if errors.Is(err, ErrContentNotFound) {
+			// The row exists, so its status changed under us.
+			return Content{}, fmt.Errorf("%w: content was changed by someone else, try again", ErrInvalidTransition)
+		}
+		return Content{}, fmt.Errorf("failed to update content: %w", err)
+	}
+	if revise {
+		log.Printf("Content %s moved from %s to %s by %s", id, existing.Status, status, actor)
+		s.loggingService.LogContentStatusChanged(id, existing.Status, status, actor, "")
+	}
+
+	if oldURL := valueOf(existing.ImageURL); oldURL != "" && oldURL != valueOf(updated.ImageURL) {
+		s.deleteImages(oldURL, existing.ThumbnailURL)
+	}
+
+	s.loggingService.LogContentUpdated(id, fields)
+
+	return updated, nil
+}
+
+// changesReviewedFields reports whether changes touch anything a reviewer
+// signed off on: the text, the theme or the image.
+func changesReviewedFields(existing Content, changes ContentChanges, newImage bool) bool {
+	switch {
+	case newImage:
+		return true
+	case changes.RemoveImage && existing.ImageURL != nil:
+		return true
+	case changes.TextEnglish != nil && *changes.TextEnglish != existing.TextEnglish:
+		return true
+	case changes.TextLatin != nil && *changes.TextLatin != valueOf(existing.TextLatin):
+		return true
+	case changes.Theme != nil && *changes.Theme != existing.Theme:
+		return true
 	}
 	return false
 }
 
+// DeleteContent removes the content with id and its image. A failure to
+// remove the image is logged but does not fail the delete.
+func (s *Service) DeleteContent(id uuid.UUID) error {
+	existing, err := s.store.GetContent(id)
+	if err != nil {
+		return fmt.Errorf("failed to get content: %w", err)
+	}
+
+	if err := s.store.DeleteContent(id); err != nil {
+		return fmt.Errorf("failed to delete content: %w", err)
+	}
+
+	if imageURL := valueOf(existing.ImageURL); imageURL != "" {
+		s.deleteImages(imageURL, existing.ThumbnailURL)
+	}
+
+	s.loggingService.LogContentDeleted(id, existing.Theme)
+
+	return nil
+}
+
+// deleteImages removes an image and, if it has one, its thumbnail.
+func (s *Service) deleteImages(imageURL string, thumbnailURL *string) {
+	s.deleteImage(imageURL)
+	if thumbnail := valueOf(thumbnailURL); thumbnail != "" {
+		s.deleteImage(thumbnail)
+	}
+}
+
+func (s *Service) deleteImage(imageURL string) {
+	err := s.images.Delete(imageURL)
+	if errors.Is(err, ErrImageNotManaged) {
+		log.Printf("Leaving image %s in place: not managed by the configured image store", imageURL)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete image %s: %v", imageURL, err)
+	}
+}
+
+// optional returns nil for empty strings so missing fields are stored as
+// NULL rather than "".
+func optional(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func valueOf(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// UpdateLastSent marks content as sent for the calendar date of
+// scheduledFor, which is what GetDailyContentFor matches on.
+func (s *Service) UpdateLastSent(id uuid.UUID, scheduledFor time.Time) error {
+	return s.store.UpdateLastSent(id, calendarDate(scheduledFor))
+}
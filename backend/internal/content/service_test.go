@@ -0,0 +1,100 @@
+package content_test
+
+import (
+	"bytes"
+	"mime/multipart"
+	"testing"
+
+	"novissima/internal/content"
+	"novissima/internal/database"
+	"novissima/internal/logging"
+	"novissima/internal/storage"
+)
+
+// newTestService returns a Service backed by the in-memory store, and the
+// store for setting up rows directly.
+func newTestService(t *testing.T) (*content.Service, content.ContentStore) {
+	t.Helper()
+
+	stores := database.NewMemoryDB().Stores()
+	service := content.NewService(
+		stores.Content,
+		stores.Overrides,
+		storage.NewLocalStore(t.TempDir(), "http://localhost/images"),
+		logging.NewService(stores.Logs),
+		content.RotationPolicy{},
+	)
+	return service, stores.Content
+}
+
+func TestUpdateApprovedContent(t *testing.T) {
+	same, changed := "Memento mori.", "Remember death."
+
+	tests := []struct {
+		name       string
+		changes    content.ContentChanges
+		wantStatus string
+	}{
+		{name: "text edit goes back to review", changes: content.ContentChanges{TextEnglish: &changed}, wantStatus: content.StatusInReview},
+		{name: "latin edit goes back to review", changes: content.ContentChanges{TextLatin: &changed}, wantStatus: content.StatusInReview},
+		{name: "unchanged text stays approved", changes: content.ContentChanges{TextEnglish: &same}, wantStatus: content.StatusApproved},
+		{name: "source edit stays approved", changes: content.ContentChanges{TextSource: &changed}, wantStatus: content.StatusApproved},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, store := newTestService(t)
+			c, err := store.InsertContent(content.ContentCreate{TextEnglish: same, Theme: "death"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, action := range []string{content.ActionSubmit, content.ActionApprove} {
+				if _, err := service.Transition(c.ID, action, "reviewer@example.com", ""); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			updated, err := service.UpdateContent(c.ID, tt.changes, nil, nil, "editor@example.com")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if updated.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", updated.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// upload is an uploaded file held in memory.
+type upload struct {
+	*bytes.Reader
+}
+
+func (upload) Close() error { return nil }
+
+func TestUpdateApprovedContentInvalidImage(t *testing.T) {
+	service, store := newTestService(t)
+	c, err := store.InsertContent(content.ContentCreate{TextEnglish: "Memento mori.", Theme: "death"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, action := range []string{content.ActionSubmit, content.ActionApprove} {
+		if _, err := service.Transition(c.ID, action, "reviewer@example.com", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	file := upload{bytes.NewReader([]byte("not an image"))}
+	header := &multipart.FileHeader{Filename: "skull.jpg"}
+	changed := "Remember death."
+	if _, err := service.UpdateContent(c.ID, content.ContentChanges{TextEnglish: &changed}, file, header, "editor@example.com"); err == nil {
+		t.Fatal("UpdateContent with an invalid image succeeded, want an error")
+	}
+
+	stored, err := service.GetContent(c.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != content.StatusApproved || stored.TextEnglish != "Memento mori." {
+		t.Errorf("content = %q, %q after a failed edit, want it unchanged", stored.Status, stored.TextEnglish)
+	}
+}
@@ -0,0 +1,105 @@
+package content
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+)
+
+// Content moves through an editorial workflow before it can be broadcast:
+// editors submit drafts for review, reviewers approve them or send them back
+// with a comment, and approved content can later be retired. Only approved
+// content is ever sent.
+const (
+	StatusDraft    = "draft"
+	StatusInReview = "in_review"
+	StatusApproved = "approved"
+	StatusRetired  = "retired"
+)
+
+var (
+	ErrInvalidTransition = errors.New("content cannot make that status change")
+	ErrCommentRequired   = errors.New("a comment is required to reject content")
+)
+
+func IsValidStatus(status string) bool {
+	switch status {
+	case StatusDraft, StatusInReview, StatusApproved, StatusRetired:
+		return true
+	}
+	return false
+}
+
+// Workflow actions, and the statuses each one moves content between.
+const (
+	ActionSubmit  = "submit"
+	ActionApprove = "approve"
+	ActionReject  = "reject"
+	ActionRetire  = "retire"
+	// ActionRevise is the move UpdateContent makes when approved content is
+	// edited, so the edit is reviewed before it is sent.
+	ActionRevise = "revise"
+)
+
+type transition struct {
+	from []string
+	to   string
+}
+
+var transitions = map[string]transition{
+	ActionSubmit:  {from: []string{StatusDraft}, to: StatusInReview},
+	ActionApprove: {from: []string{StatusInReview}, to: StatusApproved},
+	ActionReject:  {from: []string{StatusInReview}, to: StatusDraft},
+	ActionRetire:  {from: []string{StatusDraft, StatusInReview, StatusApproved}, to: StatusRetired},
+	ActionRevise:  {from: []string{StatusApproved}, to: StatusInReview},
+}
+
+// Transition applies action to the content with id on behalf of actor.
+// Rejections need a comment, which is kept on the content until it is next
+// submitted so the editor can see what to fix.
+func (s *Service) Transition(id uuid.UUID, action, actor, comment string) (Content, error) {
+	t, ok := transitions[action]
+	if !ok {
+		return Content{}, fmt.Errorf("unknown action %q", action)
+	}
+	if action == ActionReject && comment == "" {
+		return Content{}, ErrCommentRequired
+	}
+
+	current, err := s.store.GetContent(id)
+	if err != nil {
+		return Content{}, err
+	}
+	if !allowedFrom(t, current.Status) {
+		return Content{}, fmt.Errorf("%w: cannot %s %s content", ErrInvalidTransition, action, current.Status)
+	}
+
+	// Only a rejection leaves a comment; any other change clears it.
+	var reviewComment *string
+	if action == ActionReject {
+		reviewComment = &comment
+	}
+	updated, err := s.store.UpdateContentStatus(id, current.Status, t.to, reviewComment)
+	if errors.Is(err, ErrContentNotFound) {
+		// The row exists, so its status changed under us.
+		return Content{}, fmt.Errorf("%w: content was changed by someone else, try again", ErrInvalidTransition)
+	}
+	if err != nil {
+		return Content{}, fmt.Errorf("failed to update content status: %w", err)
+	}
+
+	log.Printf("Content %s moved from %s to %s by %s", id, current.Status, t.to, actor)
+	s.loggingService.LogContentStatusChanged(id, current.Status, t.to, actor, comment)
+	return updated, nil
+}
+
+func allowedFrom(t transition, status string) bool {
+	for _, from := range t.from {
+		if from == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package content
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrContentNotFound = errors.New("content not found")
+
+// ErrImageNotManaged is returned by ImageStore.Delete for URLs that point
+// somewhere else, such as images uploaded before switching backends.
+var ErrImageNotManaged = errors.New("image is not managed by this store")
+
+// ContentFilter selects content. Nil pointers and zero values mean "any".
+type ContentFilter struct {
+	Theme       string
+	Status      string
+	HasLatin    *bool
+	HasImage    *bool
+	Sent        *bool
+	Limit       int
+	Offset      int
+	NewestFirst bool
+}
+
+type ContentStore interface {
+	InsertContent(content ContentCreate) (Content, error)
+	GetContent(id uuid.UUID) (Content, error)
+	ListContent(filter ContentFilter) ([]Content, error)
+	// CountContent counts the rows matching filter, ignoring Limit and Offset.
+	CountContent(filter ContentFilter) (int, error)
+	// UpdateContent overwrites the editable fields of the row with id and
+	// moves it from status from to status to in the same write. It returns
+	// ErrContentNotFound if no row with that id is in status from.
+	UpdateContent(id uuid.UUID, content ContentCreate, from, to string) (Content, error)
+	DeleteContent(id uuid.UUID) error
+	// RestoreContent inserts content exactly as given, keeping its ID and
+	// timestamps.
+	RestoreContent(content Content) (Content, error)
+	// UpdateContentStatus moves the row with id from status from to status
+	// to, setting its review comment. It returns ErrContentNotFound if no row
+	// with that id is in status from.
+	UpdateContentStatus(id uuid.UUID, from, to string, comment *string) (Content, error)
+	UpdateLastSent(id uuid.UUID, sentAt time.Time) error
+	// UpdateContentHashes stores hashes for the row with id without touching
+	// updated_at.
+	UpdateContentHashes(id uuid.UUID, hashes ContentHashes) error
+	// FindContentByTextHash returns the rows whose stored English hash is
+	// englishHash or whose Latin hash is latinHash. A nil hash matches
+	// nothing.
+	FindContentByTextHash(englishHash, latinHash *string) ([]Content, error)
+}
+
+// ImageStore persists uploaded images and returns the public URL that is
+// saved as image_url.
+type ImageStore interface {
+	Upload(path string, data []byte, contentType string) (string, error)
+	Delete(imageURL string) error
+}
@@ -1,53 +1,21 @@
 package database
 
 import (
-	"log"
-
-	"github.com/supabase-community/supabase-go"
+	"novissima/internal/content"
+	"novissima/internal/deliveries"
+	"novissima/internal/logging"
+	"novissima/internal/users"
 )
 
-type Database interface {
-	Connect() error
-	GetClient() *supabase.Client
-}
-
-type SupabaseDB struct {
-	url      string
-	key      string
-	email    string
-	password string
-	client   *supabase.Client
-}
-
-func NewSupabaseDB(url, key, email, password string) *SupabaseDB {
-	return &SupabaseDB{
-		url:      url,
-		key:      key,
-		email:    email,
-		password: password,
-	}
+type Stores struct {
+	Users      users.UserStore
+	Content    content.ContentStore
+	Overrides  content.OverrideStore
+	Logs       logging.LogStore
+	Deliveries deliveries.DeliveryStore
 }
 
-func (db *SupabaseDB) Connect() error {
-	client, err := supabase.NewClient(db.url, db.key, nil)
-	if err != nil {
-		return err
-	}
-
-	if db.email == "" || db.password == "" {
-		log.Fatal("Missing authentication credentials in .env file")
-	}
-
-	_, err = client.Auth.SignInWithEmailPassword(db.email, db.password)
-	if err != nil {
-		log.Fatalf("Error signing in: %v", err)
-	}
-
-	db.client = client
-	return nil
-}
-
-func (db *SupabaseDB) GetClient() *supabase.Client {
-	return db.client
+type Database interface {
+	Connect() error
+	Stores() Stores
 }
-
@@ -0,0 +1,643 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"novissima/internal/content"
+	"novissima/internal/deliveries"
+	"novissima/internal/logging"
+	"novissima/internal/users"
+
+	"github.com/google/uuid"
+)
+
+// MemoryDB keeps everything in process memory. It is meant for tests and
+// local development; nothing survives a restart.
+type MemoryDB struct {
+	users      *memoryUserStore
+	content    *memoryContentStore
+	overrides  *memoryOverrideStore
+	logs       *memoryLogStore
+	deliveries *memoryDeliveryStore
+}
+
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{
+		users:      &memoryUserStore{},
+		content:    &memoryContentStore{},
+		overrides:  &memoryOverrideStore{},
+		logs:       &memoryLogStore{},
+		deliveries: &memoryDeliveryStore{},
+	}
+}
+
+func (db *MemoryDB) Connect() error {
+	return nil
+}
+
+func (db *MemoryDB) Stores() Stores {
+	return Stores{
+		Users:      db.users,
+		Content:    db.content,
+		Overrides:  db.overrides,
+		Logs:       db.logs,
+		Deliveries: db.deliveries,
+	}
+}
+
+type memoryUserStore struct {
+	mu     sync.RWMutex
+	users  []users.User
+	themes []users.ThemeFollow
+}
+
+func (s *memoryUserStore) CreateUser(user users.UserCreate) (users.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.users {
+		if existing.PhoneNumber == user.PhoneNumber {
+			return users.User{}, fmt.Errorf("user with phone number %s already exists", user.PhoneNumber)
+		}
+	}
+
+	now := time.Now()
+	created := users.User{
+		ID:            uuid.New(),
+		PhoneNumber:   user.PhoneNumber,
+		Active:        user.Active,
+		Language:      user.Language,
+		Timezone:      user.Timezone,
+		PreferredHour: user.PreferredHour,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	s.users = append(s.users, created)
+	return created, nil
+}
+
+func (s *memoryUserStore) GetActiveUsers() ([]users.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var active []users.User
+	for _, user := range s.users {
+		if user.Active {
+			active = append(active, user)
+		}
+	}
+	return active, nil
+}
+
+func (s *memoryUserStore) GetUserByPhoneNumber(phoneNumber string) (users.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.PhoneNumber == phoneNumber {
+			return user, nil
+		}
+	}
+	return users.User{}, users.ErrUserNotFound
+}
+
+func (s *memoryUserStore) UpdateUser(phoneNumber string, update users.UserUpdate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.users {
+		if s.users[i].PhoneNumber != phoneNumber {
+			continue
+		}
+		if update.Active != nil {
+			s.users[i].Active = *update.Active
+		}
+		if update.Language != nil {
+			s.users[i].Language = *update.Language
+		}
+		if update.Timezone != nil {
+			s.users[i].Timezone = *update.Timezone
+		}
+		if update.PreferredHour != nil {
+			s.users[i].PreferredHour = *update.PreferredHour
+		}
+		s.users[i].UpdatedAt = update.UpdatedAt
+		return nil
+	}
+	return users.ErrUserNotFound
+}
+
+func (s *memoryUserStore) ListUsers(filter users.UserFilter) ([]users.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matched := s.matching(filter)
+	if filter.Offset >= len(matched) {
+		return nil, nil
+	}
+	matched = matched[filter.Offset:]
+	if filter.Limit > 0 && len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+	return matched, nil
+}
+
+func (s *memoryUserStore) CountUsers(filter users.UserFilter) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.matching(filter)), nil
+}
+
+// matching returns the users selected by filter, newest first, ignoring
+// Limit and Offset. Callers must hold s.mu.
+func (s *memoryUserStore) matching(filter users.UserFilter) []users.User {
+	var found []users.User
+	for _, user := range s.users {
+		if filter.Search != "" && !strings.Contains(strings.ToLower(user.PhoneNumber), strings.ToLower(filter.Search)) {
+			continue
+		}
+		if filter.Active != nil && user.Active != *filter.Active {
+			continue
+		}
+		if filter.Language != "" && user.Language != filter.Language {
+			continue
+		}
+		if !filter.CreatedFrom.IsZero() && user.CreatedAt.Before(filter.CreatedFrom) {
+			continue
+		}
+		if !filter.CreatedBefore.IsZero() && !user.CreatedAt.Before(filter.CreatedBefore) {
+			continue
+		}
+		found = append(found, user)
+	}
+	slices.Reverse(found)
+	return found
+}
+
+func (s *memoryUserStore) FollowTheme(userID uuid.UUID, theme string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, follow := range s.themes {
+		if follow.UserID == userID && follow.Theme == theme {
+			return nil
+		}
+	}
+	s.themes = append(s.themes, users.ThemeFollow{UserID: userID, Theme: theme, CreatedAt: time.Now()})
+	return nil
+}
+
+func (s *memoryUserStore) UnfollowTheme(userID uuid.UUID, theme string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, follow := range s.themes {
+		if follow.UserID == userID && follow.Theme == theme {
+			s.themes = slices.Delete(s.themes, i, i+1)
+			return nil
+		}
+	}
+	return users.ErrNotFollowing
+}
+
+func (s *memoryUserStore) ListThemeFollows(userIDs []uuid.UUID) ([]users.ThemeFollow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var found []users.ThemeFollow
+	for _, follow := range s.themes {
+		if slices.Contains(userIDs, follow.UserID) {
+			found = append(found, follow)
+		}
+	}
+	return found, nil
+}
+
+type memoryContentStore struct {
+	mu       sync.RWMutex
+	contents []content.Content
+}
+
+func (s *memoryContentStore) InsertContent(c content.ContentCreate) (content.Content, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	created := content.Content{
+		ID:            uuid.New(),
+		TextEnglish:   c.TextEnglish,
+		TextLatin:     c.TextLatin,
+		ImageURL:      c.ImageURL,
+		Theme:         c.Theme,
+		ImageSource:   c.ImageSource,
+		TextSource:    c.TextSource,
+		ImageInfo:     c.ImageInfo,
+		ContentHashes: c.ContentHashes,
+		Status:        content.StatusDraft,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	s.contents = append(s.contents, created)
+	return created, nil
+}
+
+func (s *memoryContentStore) GetContent(id uuid.UUID) (content.Content, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, c := range s.contents {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return content.Content{}, content.ErrContentNotFound
+}
+
+func (s *memoryContentStore) ListContent(filter content.ContentFilter) ([]content.Content, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matched := s.matching(filter)
+	if filter.Offset >= len(matched) {
+		return nil, nil
+	}
+	matched = matched[filter.Offset:]
+	if filter.Limit > 0 && len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+	return matched, nil
+}
+
+func (s *memoryContentStore) CountContent(filter content.ContentFilter) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.matching(filter)), nil
+}
+
+// matching returns the contents selected by filter in created order,
+// ignoring Limit and Offset. Callers must hold s.mu.
+func (s *memoryContentStore) matching(filter content.ContentFilter) []content.Content {
+	var contents []content.Content
+	for _, c := range s.contents {
+		if filter.Theme != "" && c.Theme != filter.Theme {
+			continue
+		}
+		if filter.Status != "" && c.Status != filter.Status {
+			continue
+		}
+		if filter.HasLatin != nil && isPresent(c.TextLatin) != *filter.HasLatin {
+			continue
+		}
+		if filter.HasImage != nil && isPresent(c.ImageURL) != *filter.HasImage {
+			continue
+		}
+		if filter.Sent != nil && (c.LastSent != nil) != *filter.Sent {
+			continue
+		}
+		contents = append(contents, c)
+	}
+	if filter.NewestFirst {
+		slices.Reverse(contents)
+	}
+	return contents
+}
+
+func isPresent(value *string) bool {
+	return value != nil && *value != ""
+}
+
+func (s *memoryContentStore) UpdateContent(id uuid.UUID, c content.ContentCreate, from, to string) (content.Content, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.contents {
+		if s.contents[i].ID != id || s.contents[i].Status != from {
+			continue
+		}
+		s.contents[i].Status = to
+		s.contents[i].TextEnglish = c.TextEnglish
+		s.contents[i].TextLatin = c.TextLatin
+		s.contents[i].ImageURL = c.ImageURL
+		s.contents[i].Theme = c.Theme
+		s.contents[i].ImageSource = c.ImageSource
+		s.contents[i].TextSource = c.TextSource
+		s.contents[i].ImageInfo = c.ImageInfo
+		s.contents[i].ContentHashes = c.ContentHashes
+		s.contents[i].UpdatedAt = time.Now()
+		return s.contents[i], nil
+	}
+	return content.Content{}, content.ErrContentNotFound
+}
+
+func (s *memoryContentStore) RestoreContent(c content.Content) (content.Content, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.contents {
+		if existing.ID == c.ID {
+			return content.Content{}, fmt.Errorf("content %s already exists", c.ID)
+		}
+	}
+	s.contents = append(s.contents, c)
+	return c, nil
+}
+
+func (s *memoryContentStore) DeleteContent(id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.contents {
+		if s.contents[i].ID == id {
+			s.contents = slices.Delete(s.contents, i, i+1)
+			return nil
+		}
+	}
+	return content.ErrContentNotFound
+}
+
+func (s *memoryContentStore) UpdateContentStatus(id uuid.UUID, from, to string, comment *string) (content.Content, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.contents {
+		if s.contents[i].ID != id || s.contents[i].Status != from {
+			continue
+		}
+		s.contents[i].Status = to
+		s.contents[i].ReviewComment = comment
+		s.contents[i].UpdatedAt = time.Now()
+		return s.contents[i], nil
+	}
+	return content.Content{}, content.ErrContentNotFound
+}
+
+func (s *memoryContentStore) UpdateContentHashes(id uuid.UUID, hashes content.ContentHashes) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.contents {
+		if s.contents[i].ID == id {
+			s.contents[i].ContentHashes = hashes
+			return nil
+		}
+	}
+	return content.ErrContentNotFound
+}
+
+func (s *memoryContentStore) FindContentByTextHash(englishHash, latinHash *string) ([]content.Content, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var contents []content.Content
+	for _, c := range s.contents {
+		if sameHash(englishHash, c.EnglishHash) || sameHash(latinHash, c.LatinHash) {
+			contents = append(contents, c)
+		}
+	}
+	return contents, nil
+}
+
+func sameHash(a, b *string) bool {
+	return a != nil && b != nil && *a == *b
+}
+
+func (s *memoryContentStore) UpdateLastSent(id uuid.UUID, sentAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.contents {
+		if s.contents[i].ID == id {
+			s.contents[i].LastSent = &sentAt
+			return nil
+		}
+	}
+	return content.ErrContentNotFound
+}
+
+type memoryOverrideStore struct {
+	mu        sync.RWMutex
+	overrides []content.ScheduleOverride
+}
+
+func (s *memoryOverrideStore) InsertOverride(o content.OverrideCreate) (content.ScheduleOverride, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.overrides {
+		if existing.Date == o.Date && optionalValue(existing.Language) == optionalValue(o.Language) {
+			return content.ScheduleOverride{}, content.ErrOverrideExists
+		}
+	}
+
+	created := content.ScheduleOverride{
+		ID:        uuid.New(),
+		Date:      o.Date,
+		Language:  o.Language,
+		ContentID: o.ContentID,
+		Note:      o.Note,
+		CreatedBy: o.CreatedBy,
+		CreatedAt: time.Now(),
+	}
+	s.overrides = append(s.overrides, created)
+	return created, nil
+}
+
+func (s *memoryOverrideStore) ListOverrides(filter content.OverrideFilter) ([]content.ScheduleOverride, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var found []content.ScheduleOverride
+	for _, o := range s.overrides {
+		if filter.From != "" && o.Date < filter.From {
+			continue
+		}
+		if filter.To != "" && o.Date > filter.To {
+			continue
+		}
+		found = append(found, o)
+	}
+	slices.SortStableFunc(found, func(a, b content.ScheduleOverride) int {
+		return strings.Compare(a.Date, b.Date)
+	})
+	return found, nil
+}
+
+func (s *memoryOverrideStore) DeleteOverride(id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.overrides {
+		if s.overrides[i].ID == id {
+			s.overrides = slices.Delete(s.overrides, i, i+1)
+			return nil
+		}
+	}
+	return content.ErrOverrideNotFound
+}
+
+func optionalValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+type memoryLogStore struct {
+	mu      sync.Mutex
+	entries []logging.LogEntry
+}
+
+func (s *memoryLogStore) InsertLog(entry logging.LogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry.ID = uuid.New()
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func (s *memoryLogStore) ListLogs(filter logging.LogFilter) ([]logging.LogEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var found []logging.LogEntry
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		entry := s.entries[i]
+		if len(filter.EventTypes) > 0 && !slices.Contains(filter.EventTypes, entry.EventType) {
+			continue
+		}
+		if filter.PhoneNumber != "" {
+			var data struct {
+				PhoneNumber string `json:"phone_number"`
+			}
+			if json.Unmarshal([]byte(entry.Data), &data) != nil || data.PhoneNumber != filter.PhoneNumber {
+				continue
+			}
+		}
+		found = append(found, entry)
+		if filter.Limit > 0 && len(found) == filter.Limit {
+			break
+		}
+	}
+	return found, nil
+}
+
+type memoryDeliveryStore struct {
+	mu         sync.RWMutex
+	deliveries []deliveries.Delivery
+	events     []deliveries.DeliveryEvent
+}
+
+func (s *memoryDeliveryStore) InsertDelivery(delivery deliveries.DeliveryUpsert) (deliveries.Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.deliveries {
+		if existing.UserID == delivery.UserID && existing.ContentID == delivery.ContentID && existing.ScheduledFor == delivery.ScheduledFor {
+			return deliveries.Delivery{}, deliveries.ErrDeliveryExists
+		}
+	}
+
+	created := deliveries.Delivery{
+		ID:           uuid.New(),
+		UserID:       delivery.UserID,
+		ContentID:    delivery.ContentID,
+		ScheduledFor: delivery.ScheduledFor,
+		Status:       delivery.Status,
+		MessageSID:   delivery.MessageSID,
+		Error:        delivery.Error,
+		ErrorCode:    delivery.ErrorCode,
+		CreatedAt:    delivery.UpdatedAt,
+		UpdatedAt:    delivery.UpdatedAt,
+	}
+	s.deliveries = append(s.deliveries, created)
+	return created, nil
+}
+
+func (s *memoryDeliveryStore) UpsertDelivery(delivery deliveries.DeliveryUpsert) (deliveries.Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, existing := range s.deliveries {
+		if existing.UserID != delivery.UserID || existing.ContentID != delivery.ContentID || existing.ScheduledFor != delivery.ScheduledFor {
+			continue
+		}
+		s.deliveries[i].Status = delivery.Status
+		s.deliveries[i].MessageSID = delivery.MessageSID
+		s.deliveries[i].Error = delivery.Error
+		s.deliveries[i].ErrorCode = delivery.ErrorCode
+		s.deliveries[i].UpdatedAt = delivery.UpdatedAt
+		return s.deliveries[i], nil
+	}
+
+	created := deliveries.Delivery{
+		ID:           uuid.New(),
+		UserID:       delivery.UserID,
+		ContentID:    delivery.ContentID,
+		ScheduledFor: delivery.ScheduledFor,
+		Status:       delivery.Status,
+		MessageSID:   delivery.MessageSID,
+		Error:        delivery.Error,
+		ErrorCode:    delivery.ErrorCode,
+		CreatedAt:    delivery.UpdatedAt,
+		UpdatedAt:    delivery.UpdatedAt,
+	}
+	s.deliveries = append(s.deliveries, created)
+	return created, nil
+}
+
+func (s *memoryDeliveryStore) ListDeliveries(filter deliveries.DeliveryFilter) ([]deliveries.Delivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var found []deliveries.Delivery
+	for _, delivery := range s.deliveries {
+		if filter.UserID != nil && delivery.UserID != *filter.UserID {
+			continue
+		}
+		if filter.ContentID != nil && delivery.ContentID != *filter.ContentID {
+			continue
+		}
+		if filter.ScheduledFor != "" && delivery.ScheduledFor != filter.ScheduledFor {
+			continue
+		}
+		if len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, delivery.Status) {
+			continue
+		}
+		if filter.MessageSID != "" && (delivery.MessageSID == nil || *delivery.MessageSID != filter.MessageSID) {
+			continue
+		}
+		found = append(found, delivery)
+	}
+	return found, nil
+}
+
+func (s *memoryDeliveryStore) UpdateDelivery(id uuid.UUID, update deliveries.DeliveryUpdate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.deliveries {
+		if s.deliveries[i].ID == id {
+			s.deliveries[i].Status = update.Status
+			s.deliveries[i].ErrorCode = update.ErrorCode
+			s.deliveries[i].UpdatedAt = update.UpdatedAt
+			return nil
+		}
+	}
+	return deliveries.ErrDeliveryNotFound
+}
+
+func (s *memoryDeliveryStore) InsertDeliveryEvent(event deliveries.DeliveryEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, event)
+	return nil
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"novissima/internal/content"
+	"novissima/internal/users"
+)
+
+func TestMemoryUserStore(t *testing.T) {
+	stores := NewMemoryDB().Stores()
+	language := "la"
+
+	created, err := stores.Users.CreateUser(users.UserCreate{PhoneNumber: "+15550000001", Active: true, Language: "en"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		phoneNumber string
+		wantErr     error
+	}{
+		{name: "existing user", phoneNumber: created.PhoneNumber},
+		{name: "unknown user", phoneNumber: "+15550000002", wantErr: users.ErrUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := stores.Users.UpdateUser(tt.phoneNumber, users.UserUpdate{Language: &language, UpdatedAt: time.Now()})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateUser error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	user, err := stores.Users.GetUserByPhoneNumber(created.PhoneNumber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Language != language {
+		t.Errorf("Language = %q, want %q", user.Language, language)
+	}
+}
+
+func TestMemoryFindContentByTextHash(t *testing.T) {
+	stores := NewMemoryDB().Stores()
+	english, latin, other := "e1", "l1", "x"
+
+	first, err := stores.Content.InsertContent(content.ContentCreate{TextEnglish: "a", Theme: "death", ContentHashes: content.ContentHashes{EnglishHash: &english}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := stores.Content.InsertContent(content.ContentCreate{TextEnglish: "b", Theme: "death", ContentHashes: content.ContentHashes{LatinHash: &latin}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		english     *string
+		latin       *string
+		wantMatches int
+	}{
+		{name: "english match", english: &english, wantMatches: 1},
+		{name: "latin match", latin: &latin, wantMatches: 1},
+		{name: "either match", english: &english, latin: &latin, wantMatches: 2},
+		{name: "no match", english: &other, latin: &other},
+		{name: "nil hashes match nothing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := stores.Content.FindContentByTextHash(tt.english, tt.latin)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(found) != tt.wantMatches {
+				t.Fatalf("found %d rows, want %d", len(found), tt.wantMatches)
+			}
+			for _, c := range found {
+				if c.ID != first.ID && c.ID != second.ID {
+					t.Errorf("unexpected row %s", c.ID)
+				}
+			}
+		})
+	}
+}
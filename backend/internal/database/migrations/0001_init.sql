@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS users (
+    id           uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    phone_number text NOT NULL UNIQUE,
+    active       boolean NOT NULL DEFAULT true,
+    language     text NOT NULL DEFAULT 'en',
+    created_at   timestamptz NOT NULL DEFAULT now(),
+    updated_at   timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS content (
+    id           uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    text_english text NOT NULL,
+    text_latin   text,
+    image_url    text,
+    last_sent    timestamptz,
+    theme        text NOT NULL,
+    image_source text,
+    text_source  text,
+    created_at   timestamptz NOT NULL DEFAULT now(),
+    updated_at   timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS content_theme_idx ON content (theme);
+
+CREATE TABLE IF NOT EXISTS logs (
+    id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    event_type text NOT NULL,
+    message    text NOT NULL,
+    data       jsonb NOT NULL DEFAULT '{}',
+    created_at timestamptz NOT NULL DEFAULT now()
+);
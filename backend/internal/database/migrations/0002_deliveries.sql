@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS deliveries (
+    id            uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id       uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    content_id    uuid NOT NULL REFERENCES content (id) ON DELETE CASCADE,
+    scheduled_for date NOT NULL,
+    status        text NOT NULL,
+    message_sid   text,
+    error         text,
+    created_at    timestamptz NOT NULL DEFAULT now(),
+    updated_at    timestamptz NOT NULL DEFAULT now(),
+    UNIQUE (user_id, content_id, scheduled_for)
+);
+
+CREATE INDEX IF NOT EXISTS deliveries_content_date_idx ON deliveries (content_id, scheduled_for);
@@ -0,0 +1,13 @@
+ALTER TABLE deliveries ADD COLUMN IF NOT EXISTS error_code text;
+
+CREATE INDEX IF NOT EXISTS deliveries_message_sid_idx ON deliveries (message_sid);
+
+CREATE TABLE IF NOT EXISTS delivery_events (
+    id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    message_sid text NOT NULL,
+    status      text NOT NULL,
+    error_code  text,
+    created_at  timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS delivery_events_message_sid_idx ON delivery_events (message_sid);
@@ -0,0 +1,5 @@
+-- Log data used to be written as a JSON string by the Supabase backend;
+-- unwrap those rows so data->>'phone_number' works for every entry.
+UPDATE logs SET data = (data #>> '{}')::jsonb WHERE jsonb_typeof(data) = 'string';
+
+CREATE INDEX IF NOT EXISTS logs_phone_number_idx ON logs ((data->>'phone_number'), created_at);
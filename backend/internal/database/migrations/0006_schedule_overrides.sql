@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS schedule_overrides (
+    id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    date       date NOT NULL,
+    language   text,
+    content_id uuid NOT NULL REFERENCES content (id) ON DELETE CASCADE,
+    note       text,
+    created_by text,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- One override per date and language scope; NULL (every language) counts as
+-- its own scope.
+CREATE UNIQUE INDEX IF NOT EXISTS schedule_overrides_date_language_idx ON schedule_overrides (date, COALESCE(language, ''));
@@ -0,0 +1,15 @@
+-- Content already in the library stays eligible for broadcast; anything
+-- created from now on starts as a draft.
+ALTER TABLE content ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT 'approved';
+ALTER TABLE content ALTER COLUMN status SET DEFAULT 'draft';
+ALTER TABLE content ADD COLUMN IF NOT EXISTS review_comment text;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'content_status_check') THEN
+        ALTER TABLE content ADD CONSTRAINT content_status_check
+            CHECK (status IN ('draft', 'in_review', 'approved', 'retired'));
+    END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS content_status_idx ON content (status);
@@ -0,0 +1,4 @@
+ALTER TABLE content ADD COLUMN IF NOT EXISTS thumbnail_url text;
+ALTER TABLE content ADD COLUMN IF NOT EXISTS image_width integer;
+ALTER TABLE content ADD COLUMN IF NOT EXISTS image_height integer;
+ALTER TABLE content ADD COLUMN IF NOT EXISTS image_size bigint;
@@ -0,0 +1,8 @@
+-- Normalized fingerprints for spotting duplicate content. Rows from before
+-- this migration get them from POST /content/duplicates/backfill.
+ALTER TABLE content ADD COLUMN IF NOT EXISTS english_hash text;
+ALTER TABLE content ADD COLUMN IF NOT EXISTS latin_hash text;
+ALTER TABLE content ADD COLUMN IF NOT EXISTS image_hash text;
+
+CREATE INDEX IF NOT EXISTS content_english_hash_idx ON content (english_hash);
+CREATE INDEX IF NOT EXISTS content_latin_hash_idx ON content (latin_hash);
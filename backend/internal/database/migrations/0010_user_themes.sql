@@ -0,0 +1,8 @@
+-- Themes a user has chosen to receive. Users with no rows get the day's
+-- rotation.
+CREATE TABLE IF NOT EXISTS user_themes (
+    user_id    uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    theme      text NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, theme)
+);
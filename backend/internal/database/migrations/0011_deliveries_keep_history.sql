@@ -0,0 +1,15 @@
+-- Deleting content keeps its deliveries with no content_id, so a user's
+-- history keeps its numbering and shows the item as no longer available.
+ALTER TABLE deliveries ALTER COLUMN content_id DROP NOT NULL;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conname = 'deliveries_content_id_fkey' AND confdeltype = 'n'
+    ) THEN
+        ALTER TABLE deliveries DROP CONSTRAINT IF EXISTS deliveries_content_id_fkey;
+        ALTER TABLE deliveries ADD CONSTRAINT deliveries_content_id_fkey
+            FOREIGN KEY (content_id) REFERENCES content (id) ON DELETE SET NULL;
+    END IF;
+END $$;
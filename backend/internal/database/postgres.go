@@ -0,0 +1,727 @@
+package database
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"novissima/internal/content"
+	"novissima/internal/deliveries"
+	"novissima/internal/logging"
+	"novissima/internal/users"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// PostgresDB talks to a plain Postgres database through database/sql. The
+// schema in migrations/ is applied on Connect; every statement there is
+// idempotent so it is safe to run against an existing database, and the same
+// files can be run in the Supabase SQL editor.
+type PostgresDB struct {
+	url string
+	db  *sql.DB
+}
+
+func NewPostgresDB(url string) *PostgresDB {
+	return &PostgresDB{
+		url: url,
+	}
+}
+
+func (p *PostgresDB) Connect() error {
+	db, err := sql.Open("postgres", p.url)
+	if err != nil {
+		return fmt.Errorf("failed to open postgres: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		return fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+
+	if err := migrate(db); err != nil {
+		return err
+	}
+
+	p.db = db
+	return nil
+}
+
+func (p *PostgresDB) Stores() Stores {
+	return Stores{
+		Users:      &postgresUserStore{db: p.db},
+		Content:    &postgresContentStore{db: p.db},
+		Overrides:  &postgresOverrideStore{db: p.db},
+		Logs:       &postgresLogStore{db: p.db},
+		Deliveries: &postgresDeliveryStore{db: p.db},
+	}
+}
+
+func migrate(db *sql.DB) error {
+	entries, err := migrations.ReadDir("migrations")
+	if err != nil {
+		return fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		script, err := migrations.ReadFile("migrations/" + name)
+		if err != nil {
+			return fmt.Errorf("failed to read migration %s: %w", name, err)
+		}
+		if _, err := db.Exec(string(script)); err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+type postgresUserStore struct {
+	db *sql.DB
+}
+
+const userColumns = "id, phone_number, active, language, timezone, preferred_hour, created_at, updated_at"
+
+func scanUser(row interface{ Scan(...any) error }) (users.User, error) {
+	var user users.User
+	err := row.Scan(&user.ID, &user.PhoneNumber, &user.Active, &user.Language, &user.Timezone, &user.PreferredHour, &user.CreatedAt, &user.UpdatedAt)
+	return user, err
+}
+
+func (s *postgresUserStore) CreateUser(user users.UserCreate) (users.User, error) {
+	row := s.db.QueryRow(
+		`INSERT INTO users (phone_number, active, language, timezone, preferred_hour) VALUES ($1, $2, $3, $4, $5) RETURNING `+userColumns,
+		user.PhoneNumber, user.Active, user.Language, user.Timezone, user.PreferredHour,
+	)
+	return scanUser(row)
+}
+
+func (s *postgresUserStore) GetActiveUsers() ([]users.User, error) {
+	rows, err := s.db.Query(`SELECT ` + userColumns + ` FROM users WHERE active ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var activeUsers []users.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		activeUsers = append(activeUsers, user)
+	}
+	return activeUsers, rows.Err()
+}
+
+func (s *postgresUserStore) GetUserByPhoneNumber(phoneNumber string) (users.User, error) {
+	row := s.db.QueryRow(`SELECT `+userColumns+` FROM users WHERE phone_number = $1`, phoneNumber)
+	user, err := scanUser(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return users.User{}, users.ErrUserNotFound
+	}
+	return user, err
+}
+
+// userWhere builds the WHERE clause for filter, appending its arguments to
+// args.
+func userWhere(filter users.UserFilter, args []any) (string, []any) {
+	var conditions []string
+	if filter.Search != "" {
+		args = append(args, "%"+filter.Search+"%")
+		conditions = append(conditions, fmt.Sprintf("phone_number ILIKE $%d", len(args)))
+	}
+	if filter.Active != nil {
+		args = append(args, *filter.Active)
+		conditions = append(conditions, fmt.Sprintf("active = $%d", len(args)))
+	}
+	if filter.Language != "" {
+		args = append(args, filter.Language)
+		conditions = append(conditions, fmt.Sprintf("language = $%d", len(args)))
+	}
+	if !filter.CreatedFrom.IsZero() {
+		args = append(args, filter.CreatedFrom)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		args = append(args, filter.CreatedBefore)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func (s *postgresUserStore) ListUsers(filter users.UserFilter) ([]users.User, error) {
+	where, args := userWhere(filter, nil)
+	query := `SELECT ` + userColumns + ` FROM users` + where + " ORDER BY created_at DESC"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var found []users.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, user)
+	}
+	return found, rows.Err()
+}
+
+func (s *postgresUserStore) CountUsers(filter users.UserFilter) (int, error) {
+	where, args := userWhere(filter, nil)
+	var count int
+	err := s.db.QueryRow(`SELECT count(*) FROM users`+where, args...).Scan(&count)
+	return count, err
+}
+
+func (s *postgresUserStore) UpdateUser(phoneNumber string, update users.UserUpdate) error {
+	sets := []string{"updated_at = $1"}
+	args := []any{update.UpdatedAt}
+	if update.Active != nil {
+		args = append(args, *update.Active)
+		sets = append(sets, fmt.Sprintf("active = $%d", len(args)))
+	}
+	if update.Language != nil {
+		args = append(args, *update.Language)
+		sets = append(sets, fmt.Sprintf("language = $%d", len(args)))
+	}
+	if update.Timezone != nil {
+		args = append(args, *update.Timezone)
+		sets = append(sets, fmt.Sprintf("timezone = $%d", len(args)))
+	}
+	if update.PreferredHour != nil {
+		args = append(args, *update.PreferredHour)
+		sets = append(sets, fmt.Sprintf("preferred_hour = $%d", len(args)))
+	}
+	args = append(args, phoneNumber)
+
+	result, err := s.db.Exec(
+		fmt.Sprintf(`UPDATE users SET %s WHERE phone_number = $%d`, strings.Join(sets, ", "), len(args)),
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return users.ErrUserNotFound
+	}
+	return nil
+}
+
+func (s *postgresUserStore) FollowTheme(userID uuid.UUID, theme string) error {
+	_, err := s.db.Exec(
+		`INSERT INTO user_themes (user_id, theme) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+		userID, theme,
+	)
+	return err
+}
+
+func (s *postgresUserStore) UnfollowTheme(userID uuid.UUID, theme string) error {
+	result, err := s.db.Exec(`DELETE FROM user_themes WHERE user_id = $1 AND theme = $2`, userID, theme)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return users.ErrNotFollowing
+	}
+	return nil
+}
+
+func (s *postgresUserStore) ListThemeFollows(userIDs []uuid.UUID) ([]users.ThemeFollow, error) {
+	ids := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		ids[i] = id.String()
+	}
+
+	rows, err := s.db.Query(
+		`SELECT user_id, theme, created_at FROM user_themes WHERE user_id = ANY($1::uuid[]) ORDER BY created_at`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var found []users.ThemeFollow
+	for rows.Next() {
+		var follow users.ThemeFollow
+		if err := rows.Scan(&follow.UserID, &follow.Theme, &follow.CreatedAt); err != nil {
+			return nil, err
+		}
+		found = append(found, follow)
+	}
+	return found, rows.Err()
+}
+
+type postgresContentStore struct {
+	db *sql.DB
+}
+
+const contentColumns = "id, text_english, text_latin, image_url, last_sent, theme, image_source, text_source, thumbnail_url, image_width, image_height, image_size, english_hash, latin_hash, image_hash, status, review_comment, updated_at, created_at"
+
+func scanContent(row interface{ Scan(...any) error }) (content.Content, error) {
+	var c content.Content
+	err := row.Scan(&c.ID, &c.TextEnglish, &c.TextLatin, &c.ImageURL, &c.LastSent, &c.Theme, &c.ImageSource, &c.TextSource, &c.ThumbnailURL, &c.ImageWidth, &c.ImageHeight, &c.ImageSize, &c.EnglishHash, &c.LatinHash, &c.ImageHash, &c.Status, &c.ReviewComment, &c.UpdatedAt, &c.CreatedAt)
+	return c, err
+}
+
+func (s *postgresContentStore) InsertContent(c content.ContentCreate) (content.Content, error) {
+	row := s.db.QueryRow(
+		`INSERT INTO content (text_english, text_latin, image_url, theme, image_source, text_source, thumbnail_url, image_width, image_height, image_size,
+		english_hash, latin_hash, image_hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING `+contentColumns,
+		c.TextEnglish, c.TextLatin, c.ImageURL, c.Theme, c.ImageSource, c.TextSource, c.ThumbnailURL, c.ImageWidth, c.ImageHeight, c.ImageSize,
+		c.EnglishHash, c.LatinHash, c.ImageHash,
+	)
+	return scanContent(row)
+}
+
+func (s *postgresContentStore) GetContent(id uuid.UUID) (content.Content, error) {
+	row := s.db.QueryRow(`SELECT `+contentColumns+` FROM content WHERE id = $1`, id)
+	c, err := scanContent(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return content.Content{}, content.ErrContentNotFound
+	}
+	return c, err
+}
+
+// contentWhere builds the WHERE clause for filter, appending its arguments
+// to args. Empty strings count as missing, matching older rows.
+func contentWhere(filter content.ContentFilter, args []any) (string, []any) {
+	var conditions []string
+	if filter.Theme != "" {
+		args = append(args, filter.Theme)
+		conditions = append(conditions, fmt.Sprintf("theme = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if filter.HasLatin != nil {
+		conditions = append(conditions, presenceClause("text_latin", *filter.HasLatin))
+	}
+	if filter.HasImage != nil {
+		conditions = append(conditions, presenceClause("image_url", *filter.HasImage))
+	}
+	if filter.Sent != nil {
+		if *filter.Sent {
+			conditions = append(conditions, "last_sent IS NOT NULL")
+		} else {
+			conditions = append(conditions, "last_sent IS NULL")
+		}
+	}
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func presenceClause(column string, present bool) string {
+	if present {
+		return fmt.Sprintf("COALESCE(%s, '') <> ''", column)
+	}
+	return fmt.Sprintf("COALESCE(%s, '') = ''", column)
+}
+
+func (s *postgresContentStore) ListContent(filter content.ContentFilter) ([]content.Content, error) {
+	where, args := contentWhere(filter, nil)
+	query := `SELECT ` + contentColumns + ` FROM content` + where
+	if filter.NewestFirst {
+		query += " ORDER BY created_at DESC"
+	} else {
+		query += " ORDER BY created_at"
+	}
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contents []content.Content
+	for rows.Next() {
+		c, err := scanContent(rows)
+		if err != nil {
+			return nil, err
+		}
+		contents = append(contents, c)
+	}
+	return contents, rows.Err()
+}
+
+func (s *postgresContentStore) CountContent(filter content.ContentFilter) (int, error) {
+	where, args := contentWhere(filter, nil)
+	var count int
+	err := s.db.QueryRow(`SELECT count(*) FROM content`+where, args...).Scan(&count)
+	return count, err
+}
+
+func (s *postgresContentStore) UpdateContent(id uuid.UUID, c content.ContentCreate, from, to string) (content.Content, error) {
+	row := s.db.QueryRow(
+		`UPDATE content SET text_english = $1, text_latin = $2, image_url = $3, theme = $4,
+		image_source = $5, text_source = $6, thumbnail_url = $7, image_width = $8, image_height = $9,
+		image_size = $10, english_hash = $11, latin_hash = $12, image_hash = $13, status = $14, updated_at = now()
+		WHERE id = $15 AND status = $16 RETURNING `+contentColumns,
+		c.TextEnglish, c.TextLatin, c.ImageURL, c.Theme, c.ImageSource, c.TextSource, c.ThumbnailURL, c.ImageWidth, c.ImageHeight, c.ImageSize,
+		c.EnglishHash, c.LatinHash, c.ImageHash, to, id, from,
+	)
+	updated, err := scanContent(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return content.Content{}, content.ErrContentNotFound
+	}
+	return updated, err
+}
+
+func (s *postgresContentStore) RestoreContent(c content.Content) (content.Content, error) {
+	row := s.db.QueryRow(
+		`INSERT INTO content (id, text_english, text_latin, image_url, last_sent, theme, image_source, text_source,
+		thumbnail_url, image_width, image_height, image_size, english_hash, latin_hash, image_hash, status, review_comment, updated_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19) RETURNING `+contentColumns,
+		c.ID, c.TextEnglish, c.TextLatin, c.ImageURL, c.LastSent, c.Theme, c.ImageSource, c.TextSource,
+		c.ThumbnailURL, c.ImageWidth, c.ImageHeight, c.ImageSize, c.EnglishHash, c.LatinHash, c.ImageHash, c.Status, c.ReviewComment, c.UpdatedAt, c.CreatedAt,
+	)
+	return scanContent(row)
+}
+
+func (s *postgresContentStore) DeleteContent(id uuid.UUID) error {
+	result, err := s.db.Exec(`DELETE FROM content WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return content.ErrContentNotFound
+	}
+	return nil
+}
+
+func (s *postgresContentStore) UpdateContentStatus(id uuid.UUID, from, to string, comment *string) (content.Content, error) {
+	row := s.db.QueryRow(
+		`UPDATE content SET status = $1, review_comment = $2, updated_at = now()
+		WHERE id = $3 AND status = $4 RETURNING `+contentColumns,
+		to, comment, id, from,
+	)
+	updated, err := scanContent(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return content.Content{}, content.ErrContentNotFound
+	}
+	return updated, err
+}
+
+func (s *postgresContentStore) UpdateContentHashes(id uuid.UUID, hashes content.ContentHashes) error {
+	result, err := s.db.Exec(
+		`UPDATE content SET english_hash = $1, latin_hash = $2, image_hash = $3 WHERE id = $4`,
+		hashes.EnglishHash, hashes.LatinHash, hashes.ImageHash, id,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return content.ErrContentNotFound
+	}
+	return nil
+}
+
+func (s *postgresContentStore) FindContentByTextHash(englishHash, latinHash *string) ([]content.Content, error) {
+	if englishHash == nil && latinHash == nil {
+		return nil, nil
+	}
+	rows, err := s.db.Query(
+		`SELECT `+contentColumns+` FROM content WHERE english_hash = $1 OR latin_hash = $2 ORDER BY created_at`,
+		englishHash, latinHash,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contents []content.Content
+	for rows.Next() {
+		c, err := scanContent(rows)
+		if err != nil {
+			return nil, err
+		}
+		contents = append(contents, c)
+	}
+	return contents, rows.Err()
+}
+
+func (s *postgresContentStore) UpdateLastSent(id uuid.UUID, sentAt time.Time) error {
+	result, err := s.db.Exec(`UPDATE content SET last_sent = $1 WHERE id = $2`, sentAt, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return content.ErrContentNotFound
+	}
+	return nil
+}
+
+type postgresOverrideStore struct {
+	db *sql.DB
+}
+
+const overrideColumns = "id, date::text, language, content_id, note, created_by, created_at"
+
+func scanOverride(row interface{ Scan(...any) error }) (content.ScheduleOverride, error) {
+	var o content.ScheduleOverride
+	err := row.Scan(&o.ID, &o.Date, &o.Language, &o.ContentID, &o.Note, &o.CreatedBy, &o.CreatedAt)
+	return o, err
+}
+
+func (s *postgresOverrideStore) InsertOverride(o content.OverrideCreate) (content.ScheduleOverride, error) {
+	row := s.db.QueryRow(
+		`INSERT INTO schedule_overrides (date, language, content_id, note, created_by)
+		VALUES ($1, $2, $3, $4, $5) RETURNING `+overrideColumns,
+		o.Date, o.Language, o.ContentID, o.Note, o.CreatedBy,
+	)
+	created, err := scanOverride(row)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return content.ScheduleOverride{}, content.ErrOverrideExists
+	}
+	return created, err
+}
+
+func (s *postgresOverrideStore) ListOverrides(filter content.OverrideFilter) ([]content.ScheduleOverride, error) {
+	var conditions []string
+	var args []any
+	if filter.From != "" {
+		args = append(args, filter.From)
+		conditions = append(conditions, fmt.Sprintf("date >= $%d", len(args)))
+	}
+	if filter.To != "" {
+		args = append(args, filter.To)
+		conditions = append(conditions, fmt.Sprintf("date <= $%d", len(args)))
+	}
+
+	query := `SELECT ` + overrideColumns + ` FROM schedule_overrides`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY date, created_at"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var found []content.ScheduleOverride
+	for rows.Next() {
+		o, err := scanOverride(rows)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, o)
+	}
+	return found, rows.Err()
+}
+
+func (s *postgresOverrideStore) DeleteOverride(id uuid.UUID) error {
+	result, err := s.db.Exec(`DELETE FROM schedule_overrides WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return content.ErrOverrideNotFound
+	}
+	return nil
+}
+
+type postgresLogStore struct {
+	db *sql.DB
+}
+
+func (s *postgresLogStore) InsertLog(entry logging.LogEntry) error {
+	_, err := s.db.Exec(
+		`INSERT INTO logs (event_type, message, data, created_at) VALUES ($1, $2, $3::jsonb, $4)`,
+		entry.EventType, entry.Message, entry.Data, entry.CreatedAt,
+	)
+	return err
+}
+
+func (s *postgresLogStore) ListLogs(filter logging.LogFilter) ([]logging.LogEntry, error) {
+	var conditions []string
+	var args []any
+	if len(filter.EventTypes) > 0 {
+		args = append(args, pq.Array(filter.EventTypes))
+		conditions = append(conditions, fmt.Sprintf("event_type = ANY($%d)", len(args)))
+	}
+	if filter.PhoneNumber != "" {
+		args = append(args, filter.PhoneNumber)
+		conditions = append(conditions, fmt.Sprintf("data->>'phone_number' = $%d", len(args)))
+	}
+
+	query := `SELECT id, event_type, message, data::text, created_at FROM logs`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at DESC"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []logging.LogEntry
+	for rows.Next() {
+		var entry logging.LogEntry
+		if err := rows.Scan(&entry.ID, &entry.EventType, &entry.Message, &entry.Data, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+type postgresDeliveryStore struct {
+	db *sql.DB
+}
+
+const deliveryColumns = "id, user_id, content_id, scheduled_for::text, status, message_sid, error, error_code, created_at, updated_at"
+
+func scanDelivery(row interface{ Scan(...any) error }) (deliveries.Delivery, error) {
+	var d deliveries.Delivery
+	err := row.Scan(&d.ID, &d.UserID, &d.ContentID, &d.ScheduledFor, &d.Status, &d.MessageSID, &d.Error, &d.ErrorCode, &d.CreatedAt, &d.UpdatedAt)
+	return d, err
+}
+
+func (s *postgresDeliveryStore) InsertDelivery(delivery deliveries.DeliveryUpsert) (deliveries.Delivery, error) {
+	row := s.db.QueryRow(
+		`INSERT INTO deliveries (user_id, content_id, scheduled_for, status, message_sid, error, error_code, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING `+deliveryColumns,
+		delivery.UserID, delivery.ContentID, delivery.ScheduledFor, delivery.Status, delivery.MessageSID, delivery.Error, delivery.ErrorCode, delivery.UpdatedAt,
+	)
+	created, err := scanDelivery(row)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return deliveries.Delivery{}, deliveries.ErrDeliveryExists
+	}
+	return created, err
+}
+
+func (s *postgresDeliveryStore) UpsertDelivery(delivery deliveries.DeliveryUpsert) (deliveries.Delivery, error) {
+	row := s.db.QueryRow(
+		`INSERT INTO deliveries (user_id, content_id, scheduled_for, status, message_sid, error, error_code, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (user_id, content_id, scheduled_for) DO UPDATE SET
+			status = EXCLUDED.status,
+			message_sid = EXCLUDED.message_sid,
+			error = EXCLUDED.error,
+			error_code = EXCLUDED.error_code,
+			updated_at = EXCLUDED.updated_at
+		RETURNING `+deliveryColumns,
+		delivery.UserID, delivery.ContentID, delivery.ScheduledFor, delivery.Status, delivery.MessageSID, delivery.Error, delivery.ErrorCode, delivery.UpdatedAt,
+	)
+	return scanDelivery(row)
+}
+
+func (s *postgresDeliveryStore) ListDeliveries(filter deliveries.DeliveryFilter) ([]deliveries.Delivery, error) {
+	var conditions []string
+	var args []any
+	if filter.UserID != nil {
+		args = append(args, *filter.UserID)
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+	if filter.ContentID != nil {
+		args = append(args, *filter.ContentID)
+		conditions = append(conditions, fmt.Sprintf("content_id = $%d", len(args)))
+	}
+	if filter.ScheduledFor != "" {
+		args = append(args, filter.ScheduledFor)
+		conditions = append(conditions, fmt.Sprintf("scheduled_for = $%d", len(args)))
+	}
+	if len(filter.Statuses) > 0 {
+		args = append(args, pq.Array(filter.Statuses))
+		conditions = append(conditions, fmt.Sprintf("status = ANY($%d)", len(args)))
+	}
+	if filter.MessageSID != "" {
+		args = append(args, filter.MessageSID)
+		conditions = append(conditions, fmt.Sprintf("message_sid = $%d", len(args)))
+	}
+
+	query := `SELECT ` + deliveryColumns + ` FROM deliveries`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var found []deliveries.Delivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, d)
+	}
+	return found, rows.Err()
+}
+
+func (s *postgresDeliveryStore) UpdateDelivery(id uuid.UUID, update deliveries.DeliveryUpdate) error {
+	result, err := s.db.Exec(
+		`UPDATE deliveries SET status = $1, error_code = $2, updated_at = $3 WHERE id = $4`,
+		update.Status, update.ErrorCode, update.UpdatedAt, id,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return deliveries.ErrDeliveryNotFound
+	}
+	return nil
+}
+
+func (s *postgresDeliveryStore) InsertDeliveryEvent(event deliveries.DeliveryEvent) error {
+	_, err := s.db.Exec(
+		`INSERT INTO delivery_events (message_sid, status, error_code, created_at) VALUES ($1, $2, $3, $4)`,
+		event.MessageSID, event.Status, event.ErrorCode, event.CreatedAt,
+	)
+	return err
+}
@@ -0,0 +1,739 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"novissima/internal/content"
+	"novissima/internal/deliveries"
+	"novissima/internal/logging"
+	"novissima/internal/users"
+
+	"github.com/google/uuid"
+	"github.com/supabase-community/postgrest-go"
+	"github.com/supabase-community/supabase-go"
+)
+
+type SupabaseDB struct {
+	url      string
+	key      string
+	email    string
+	password string
+	client   *supabase.Client
+}
+
+func NewSupabaseDB(url, key, email, password string) *SupabaseDB {
+	return &SupabaseDB{
+		url:      url,
+		key:      key,
+		email:    email,
+		password: password,
+	}
+}
+
+func (db *SupabaseDB) Connect() error {
+	client, err := supabase.NewClient(db.url, db.key, nil)
+	if err != nil {
+		return err
+	}
+
+	if db.email == "" || db.password == "" {
+		log.Fatal("Missing authentication credentials in .env file")
+	}
+
+	_, err = client.Auth.SignInWithEmailPassword(db.email, db.password)
+	if err != nil {
+		log.Fatalf("Error signing in: %v", err)
+	}
+
+	db.client = client
+	return nil
+}
+
+func (db *SupabaseDB) Client() *supabase.Client {
+	return db.client
+}
+
+func (db *SupabaseDB) Stores() Stores {
+	return Stores{
+		Users:      &supabaseUserStore{client: db.client},
+		Content:    &supabaseContentStore{client: db.client},
+		Overrides:  &supabaseOverrideStore{client: db.client},
+		Logs:       &supabaseLogStore{client: db.client},
+		Deliveries: &supabaseDeliveryStore{client: db.client},
+	}
+}
+
+type supabaseUserStore struct {
+	client *supabase.Client
+}
+
+func (s *supabaseUserStore) CreateUser(user users.UserCreate) (users.User, error) {
+	data, _, err := s.client.From("users").Insert(user, true, "", "", "").Execute()
+	if err != nil {
+		return users.User{}, err
+	}
+
+	var createdUsers []users.User
+	if err := json.Unmarshal(data, &createdUsers); err != nil {
+		return users.User{}, fmt.Errorf("failed to parse created user: %w", err)
+	}
+
+	if len(createdUsers) == 0 {
+		return users.User{}, fmt.Errorf("no user was created")
+	}
+
+	return createdUsers[0], nil
+}
+
+func (s *supabaseUserStore) GetActiveUsers() ([]users.User, error) {
+	data, _, err := s.client.From("users").
+		Select("*", "", false).
+		Eq("active", "true").
+		Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	var activeUsers []users.User
+	if err := json.Unmarshal(data, &activeUsers); err != nil {
+		return nil, fmt.Errorf("failed to parse users data: %w", err)
+	}
+
+	return activeUsers, nil
+}
+
+func (s *supabaseUserStore) GetUserByPhoneNumber(phoneNumber string) (users.User, error) {
+	data, _, err := s.client.From("users").
+		Select("*", "", false).
+		Eq("phone_number", phoneNumber).
+		Execute()
+	if err != nil {
+		return users.User{}, err
+	}
+
+	var found []users.User
+	if err := json.Unmarshal(data, &found); err != nil {
+		return users.User{}, fmt.Errorf("failed to parse user data: %w", err)
+	}
+
+	if len(found) == 0 {
+		return users.User{}, users.ErrUserNotFound
+	}
+
+	return found[0], nil
+}
+
+func (s *supabaseUserStore) UpdateUser(phoneNumber string, update users.UserUpdate) error {
+	data, _, err := s.client.From("users").
+		Update(update, "representation", "").
+		Eq("phone_number", phoneNumber).
+		Execute()
+	if err != nil {
+		return err
+	}
+
+	var updated []users.User
+	if err := json.Unmarshal(data, &updated); err != nil {
+		return fmt.Errorf("failed to parse updated user: %w", err)
+	}
+
+	if len(updated) == 0 {
+		return users.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (s *supabaseUserStore) ListUsers(filter users.UserFilter) ([]users.User, error) {
+	query := applySupabaseUserFilter(s.client.From("users").Select("*", "", false), filter)
+	query = query.Order("created_at", &postgrest.OrderOpts{Ascending: false})
+	if filter.Limit > 0 {
+		query = query.Range(filter.Offset, filter.Offset+filter.Limit-1, "")
+	}
+
+	data, _, err := query.Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	var found []users.User
+	if err := json.Unmarshal(data, &found); err != nil {
+		return nil, fmt.Errorf("failed to parse user data: %w", err)
+	}
+
+	return found, nil
+}
+
+func (s *supabaseUserStore) CountUsers(filter users.UserFilter) (int, error) {
+	query := applySupabaseUserFilter(s.client.From("users").Select("id", "exact", true), filter)
+	_, count, err := query.Execute()
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+func applySupabaseUserFilter(query *postgrest.FilterBuilder, filter users.UserFilter) *postgrest.FilterBuilder {
+	if filter.Search != "" {
+		query = query.Ilike("phone_number", "*"+filter.Search+"*")
+	}
+	if filter.Active != nil {
+		query = query.Eq("active", strconv.FormatBool(*filter.Active))
+	}
+	if filter.Language != "" {
+		query = query.Eq("language", filter.Language)
+	}
+
+	// Both bounds are on created_at, so they share one and=(...) clause.
+	var conditions []string
+	if !filter.CreatedFrom.IsZero() {
+		conditions = append(conditions, "created_at.gte."+filter.CreatedFrom.UTC().Format(time.RFC3339))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		conditions = append(conditions, "created_at.lt."+filter.CreatedBefore.UTC().Format(time.RFC3339))
+	}
+	if len(conditions) > 0 {
+		query = query.And(strings.Join(conditions, ","), "")
+	}
+	return query
+}
+
+func (s *supabaseUserStore) FollowTheme(userID uuid.UUID, theme string) error {
+	follow := map[string]string{"user_id": userID.String(), "theme": theme}
+	_, _, err := s.client.From("user_themes").Insert(follow, true, "user_id,theme", "minimal", "").Execute()
+	return err
+}
+
+func (s *supabaseUserStore) UnfollowTheme(userID uuid.UUID, theme string) error {
+	data, _, err := s.client.From("user_themes").
+		Delete("", "").
+		Eq("user_id", userID.String()).
+		Eq("theme", theme).
+		Execute()
+	if err != nil {
+		return err
+	}
+
+	var deleted []users.ThemeFollow
+	if err := json.Unmarshal(data, &deleted); err != nil {
+		return fmt.Errorf("failed to parse deleted theme follow: %w", err)
+	}
+
+	if len(deleted) == 0 {
+		return users.ErrNotFollowing
+	}
+
+	return nil
+}
+
+// themeFollowBatch caps the user IDs per request, as they go in the URL.
+const themeFollowBatch = 200
+
+func (s *supabaseUserStore) ListThemeFollows(userIDs []uuid.UUID) ([]users.ThemeFollow, error) {
+	var found []users.ThemeFollow
+	for start := 0; start < len(userIDs); start += themeFollowBatch {
+		batch := userIDs[start:min(start+themeFollowBatch, len(userIDs))]
+		ids := make([]string, len(batch))
+		for i, id := range batch {
+			ids[i] = id.String()
+		}
+
+		data, _, err := s.client.From("user_themes").
+			Select("*", "", false).
+			In("user_id", ids).
+			Order("created_at", &postgrest.OrderOpts{Ascending: true}).
+			Execute()
+		if err != nil {
+			return nil, err
+		}
+
+		var follows []users.ThemeFollow
+		if err := json.Unmarshal(data, &follows); err != nil {
+			return nil, fmt.Errorf("failed to parse theme follows: %w", err)
+		}
+		found = append(found, follows...)
+	}
+
+	return found, nil
+}
+
+type supabaseContentStore struct {
+	client *supabase.Client
+}
+
+func (s *supabaseContentStore) InsertContent(c content.ContentCreate) (content.Content, error) {
+	data, _, err := s.client.From("content").Insert(c, true, "", "", "").Execute()
+	if err != nil {
+		return content.Content{}, err
+	}
+
+	var created []content.Content
+	if err := json.Unmarshal(data, &created); err != nil {
+		return content.Content{}, fmt.Errorf("failed to parse created content: %w", err)
+	}
+
+	if len(created) == 0 {
+		return content.Content{}, fmt.Errorf("no content was created")
+	}
+
+	return created[0], nil
+}
+
+func (s *supabaseContentStore) GetContent(id uuid.UUID) (content.Content, error) {
+	data, _, err := s.client.From("content").Select("*", "", false).Eq("id", id.String()).Execute()
+	if err != nil {
+		return content.Content{}, err
+	}
+
+	var found []content.Content
+	if err := json.Unmarshal(data, &found); err != nil {
+		return content.Content{}, fmt.Errorf("failed to parse content: %w", err)
+	}
+
+	if len(found) == 0 {
+		return content.Content{}, content.ErrContentNotFound
+	}
+
+	return found[0], nil
+}
+
+func (s *supabaseContentStore) ListContent(filter content.ContentFilter) ([]content.Content, error) {
+	query := applySupabaseContentFilter(s.client.From("content").Select("*", "", false), filter)
+	query = query.Order("created_at", &postgrest.OrderOpts{Ascending: !filter.NewestFirst})
+	if filter.Limit > 0 {
+		query = query.Range(filter.Offset, filter.Offset+filter.Limit-1, "")
+	}
+
+	data, _, err := query.Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	var contents []content.Content
+	if err := json.Unmarshal(data, &contents); err != nil {
+		return nil, fmt.Errorf("failed to parse content: %w", err)
+	}
+
+	return contents, nil
+}
+
+func (s *supabaseContentStore) CountContent(filter content.ContentFilter) (int, error) {
+	query := applySupabaseContentFilter(s.client.From("content").Select("id", "exact", true), filter)
+	_, count, err := query.Execute()
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+// applySupabaseContentFilter adds filter's conditions to query. PostgREST
+// keys filters by column, so the per-column conditions are combined into a
+// single and=(...) clause instead of being added one by one.
+func applySupabaseContentFilter(query *postgrest.FilterBuilder, filter content.ContentFilter) *postgrest.FilterBuilder {
+	if filter.Theme != "" {
+		query = query.Eq("theme", filter.Theme)
+	}
+	if filter.Status != "" {
+		query = query.Eq("status", filter.Status)
+	}
+
+	var conditions []string
+	if filter.HasLatin != nil {
+		conditions = append(conditions, presenceCondition("text_latin", *filter.HasLatin))
+	}
+	if filter.HasImage != nil {
+		conditions = append(conditions, presenceCondition("image_url", *filter.HasImage))
+	}
+	if filter.Sent != nil {
+		if *filter.Sent {
+			conditions = append(conditions, "last_sent.not.is.null")
+		} else {
+			conditions = append(conditions, "last_sent.is.null")
+		}
+	}
+	if len(conditions) > 0 {
+		query = query.And(strings.Join(conditions, ","), "")
+	}
+	return query
+}
+
+// presenceCondition treats empty strings like NULL, since older rows were
+// saved with "" for missing optional fields.
+func presenceCondition(column string, present bool) string {
+	if present {
+		return fmt.Sprintf(`and(%s.not.is.null,%s.neq."")`, column, column)
+	}
+	return fmt.Sprintf(`or(%s.is.null,%s.eq."")`, column, column)
+}
+
+func (s *supabaseContentStore) UpdateContent(id uuid.UUID, c content.ContentCreate, from, to string) (content.Content, error) {
+	update := supabaseContentUpdate{ContentCreate: c, Status: to, UpdatedAt: time.Now()}
+	data, _, err := s.client.From("content").
+		Update(update, "", "").
+		Eq("id", id.String()).
+		Eq("status", from).
+		Execute()
+	if err != nil {
+		return content.Content{}, err
+	}
+
+	var updated []content.Content
+	if err := json.Unmarshal(data, &updated); err != nil {
+		return content.Content{}, fmt.Errorf("failed to parse updated content: %w", err)
+	}
+
+	if len(updated) == 0 {
+		return content.Content{}, content.ErrContentNotFound
+	}
+
+	return updated[0], nil
+}
+
+type supabaseContentUpdate struct {
+	content.ContentCreate
+	Status    string    `json:"status"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (s *supabaseContentStore) UpdateContentStatus(id uuid.UUID, from, to string, comment *string) (content.Content, error) {
+	update := map[string]interface{}{
+		"status":         to,
+		"review_comment": comment,
+		"updated_at":     time.Now(),
+	}
+	data, _, err := s.client.From("content").
+		Update(update, "", "").
+		Eq("id", id.String()).
+		Eq("status", from).
+		Execute()
+	if err != nil {
+		return content.Content{}, err
+	}
+
+	var updated []content.Content
+	if err := json.Unmarshal(data, &updated); err != nil {
+		return content.Content{}, fmt.Errorf("failed to parse updated content: %w", err)
+	}
+
+	if len(updated) == 0 {
+		return content.Content{}, content.ErrContentNotFound
+	}
+
+	return updated[0], nil
+}
+
+func (s *supabaseContentStore) RestoreContent(c content.Content) (content.Content, error) {
+	data, _, err := s.client.From("content").Insert(c, false, "", "", "").Execute()
+	if err != nil {
+		return content.Content{}, err
+	}
+
+	var restored []content.Content
+	if err := json.Unmarshal(data, &restored); err != nil {
+		return content.Content{}, fmt.Errorf("failed to parse restored content: %w", err)
+	}
+
+	if len(restored) == 0 {
+		return content.Content{}, fmt.Errorf("no content was restored")
+	}
+
+	return restored[0], nil
+}
+
+func (s *supabaseContentStore) DeleteContent(id uuid.UUID) error {
+	data, _, err := s.client.From("content").Delete("", "").Eq("id", id.String()).Execute()
+	if err != nil {
+		return err
+	}
+
+	var deleted []content.Content
+	if err := json.Unmarshal(data, &deleted); err != nil {
+		return fmt.Errorf("failed to parse deleted content: %w", err)
+	}
+
+	if len(deleted) == 0 {
+		return content.ErrContentNotFound
+	}
+
+	return nil
+}
+
+func (s *supabaseContentStore) UpdateContentHashes(id uuid.UUID, hashes content.ContentHashes) error {
+	data, _, err := s.client.From("content").Update(hashes, "", "").Eq("id", id.String()).Execute()
+	if err != nil {
+		return err
+	}
+
+	var updated []content.Content
+	if err := json.Unmarshal(data, &updated); err != nil {
+		return fmt.Errorf("failed to parse updated content: %w", err)
+	}
+
+	if len(updated) == 0 {
+		return content.ErrContentNotFound
+	}
+
+	return nil
+}
+
+func (s *supabaseContentStore) FindContentByTextHash(englishHash, latinHash *string) ([]content.Content, error) {
+	var conditions []string
+	if englishHash != nil {
+		conditions = append(conditions, "english_hash.eq."+*englishHash)
+	}
+	if latinHash != nil {
+		conditions = append(conditions, "latin_hash.eq."+*latinHash)
+	}
+	if len(conditions) == 0 {
+		return nil, nil
+	}
+
+	data, _, err := s.client.From("content").Select("*", "", false).
+		Or(strings.Join(conditions, ","), "").
+		Order("created_at", &postgrest.OrderOpts{Ascending: true}).
+		Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	var contents []content.Content
+	if err := json.Unmarshal(data, &contents); err != nil {
+		return nil, fmt.Errorf("failed to parse content: %w", err)
+	}
+	return contents, nil
+}
+
+func (s *supabaseContentStore) UpdateLastSent(id uuid.UUID, sentAt time.Time) error {
+	_, _, err := s.client.From("content").Update(map[string]interface{}{
+		"last_sent": sentAt,
+	}, "", "").Eq("id", id.String()).Execute()
+	return err
+}
+
+type supabaseLogStore struct {
+	client *supabase.Client
+}
+
+// supabaseLogRow mirrors the logs table. Data is jsonb, so it has to be sent
+// as raw JSON; a Go string would be stored as a JSON string scalar.
+type supabaseLogRow struct {
+	ID        *uuid.UUID      `json:"id,omitempty"`
+	EventType string          `json:"event_type"`
+	Message   string          `json:"message"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+func (s *supabaseLogStore) InsertLog(entry logging.LogEntry) error {
+	_, _, err := s.client.From("logs").Insert(supabaseLogRow{
+		EventType: entry.EventType,
+		Message:   entry.Message,
+		Data:      json.RawMessage(entry.Data),
+		CreatedAt: entry.CreatedAt,
+	}, false, "", "minimal", "").Execute()
+	return err
+}
+
+func (s *supabaseLogStore) ListLogs(filter logging.LogFilter) ([]logging.LogEntry, error) {
+	query := s.client.From("logs").Select("*", "", false)
+	if len(filter.EventTypes) > 0 {
+		query = query.In("event_type", filter.EventTypes)
+	}
+	if filter.PhoneNumber != "" {
+		query = query.Eq("data->>phone_number", filter.PhoneNumber)
+	}
+	query = query.Order("created_at", &postgrest.OrderOpts{Ascending: false})
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit, "")
+	}
+
+	data, _, err := query.Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []supabaseLogRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse log entries: %w", err)
+	}
+
+	entries := make([]logging.LogEntry, 0, len(rows))
+	for _, row := range rows {
+		entry := logging.LogEntry{
+			EventType: row.EventType,
+			Message:   row.Message,
+			Data:      string(row.Data),
+			CreatedAt: row.CreatedAt,
+		}
+		if row.ID != nil {
+			entry.ID = *row.ID
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+type supabaseOverrideStore struct {
+	client *supabase.Client
+}
+
+func (s *supabaseOverrideStore) InsertOverride(o content.OverrideCreate) (content.ScheduleOverride, error) {
+	data, _, err := s.client.From("schedule_overrides").Insert(o, false, "", "", "").Execute()
+	if err != nil {
+		if strings.Contains(err.Error(), "23505") {
+			return content.ScheduleOverride{}, content.ErrOverrideExists
+		}
+		return content.ScheduleOverride{}, err
+	}
+
+	var created []content.ScheduleOverride
+	if err := json.Unmarshal(data, &created); err != nil {
+		return content.ScheduleOverride{}, fmt.Errorf("failed to parse created override: %w", err)
+	}
+
+	if len(created) == 0 {
+		return content.ScheduleOverride{}, fmt.Errorf("no override was created")
+	}
+
+	return created[0], nil
+}
+
+func (s *supabaseOverrideStore) ListOverrides(filter content.OverrideFilter) ([]content.ScheduleOverride, error) {
+	query := s.client.From("schedule_overrides").Select("*", "", false)
+
+	// Both bounds are on date, so they share one and=(...) clause.
+	var conditions []string
+	if filter.From != "" {
+		conditions = append(conditions, "date.gte."+filter.From)
+	}
+	if filter.To != "" {
+		conditions = append(conditions, "date.lte."+filter.To)
+	}
+	if len(conditions) > 0 {
+		query = query.And(strings.Join(conditions, ","), "")
+	}
+
+	data, _, err := query.Order("date", &postgrest.OrderOpts{Ascending: true}).Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	var found []content.ScheduleOverride
+	if err := json.Unmarshal(data, &found); err != nil {
+		return nil, fmt.Errorf("failed to parse overrides: %w", err)
+	}
+
+	return found, nil
+}
+
+func (s *supabaseOverrideStore) DeleteOverride(id uuid.UUID) error {
+	data, _, err := s.client.From("schedule_overrides").Delete("", "").Eq("id", id.String()).Execute()
+	if err != nil {
+		return err
+	}
+
+	var deleted []content.ScheduleOverride
+	if err := json.Unmarshal(data, &deleted); err != nil {
+		return fmt.Errorf("failed to parse deleted override: %w", err)
+	}
+
+	if len(deleted) == 0 {
+		return content.ErrOverrideNotFound
+	}
+
+	return nil
+}
+
+type supabaseDeliveryStore struct {
+	client *supabase.Client
+}
+
+func (s *supabaseDeliveryStore) InsertDelivery(delivery deliveries.DeliveryUpsert) (deliveries.Delivery, error) {
+	data, _, err := s.client.From("deliveries").Insert(delivery, false, "", "", "").Execute()
+	if err != nil {
+		if strings.Contains(err.Error(), "23505") {
+			return deliveries.Delivery{}, deliveries.ErrDeliveryExists
+		}
+		return deliveries.Delivery{}, err
+	}
+
+	var created []deliveries.Delivery
+	if err := json.Unmarshal(data, &created); err != nil {
+		return deliveries.Delivery{}, fmt.Errorf("failed to parse delivery: %w", err)
+	}
+
+	if len(created) == 0 {
+		return deliveries.Delivery{}, fmt.Errorf("no delivery was recorded")
+	}
+
+	return created[0], nil
+}
+
+func (s *supabaseDeliveryStore) UpsertDelivery(delivery deliveries.DeliveryUpsert) (deliveries.Delivery, error) {
+	data, _, err := s.client.From("deliveries").
+		Insert(delivery, true, "user_id,content_id,scheduled_for", "", "").
+		Execute()
+	if err != nil {
+		return deliveries.Delivery{}, err
+	}
+
+	var upserted []deliveries.Delivery
+	if err := json.Unmarshal(data, &upserted); err != nil {
+		return deliveries.Delivery{}, fmt.Errorf("failed to parse delivery: %w", err)
+	}
+
+	if len(upserted) == 0 {
+		return deliveries.Delivery{}, fmt.Errorf("no delivery was recorded")
+	}
+
+	return upserted[0], nil
+}
+
+func (s *supabaseDeliveryStore) ListDeliveries(filter deliveries.DeliveryFilter) ([]deliveries.Delivery, error) {
+	query := s.client.From("deliveries").Select("*", "", false)
+	if filter.UserID != nil {
+		query = query.Eq("user_id", filter.UserID.String())
+	}
+	if filter.ContentID != nil {
+		query = query.Eq("content_id", filter.ContentID.String())
+	}
+	if filter.ScheduledFor != "" {
+		query = query.Eq("scheduled_for", filter.ScheduledFor)
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.In("status", filter.Statuses)
+	}
+	if filter.MessageSID != "" {
+		query = query.Eq("message_sid", filter.MessageSID)
+	}
+
+	data, _, err := query.Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	var found []deliveries.Delivery
+	if err := json.Unmarshal(data, &found); err != nil {
+		return nil, fmt.Errorf("failed to parse deliveries: %w", err)
+	}
+
+	return found, nil
+}
+
+func (s *supabaseDeliveryStore) UpdateDelivery(id uuid.UUID, update deliveries.DeliveryUpdate) error {
+	_, _, err := s.client.From("deliveries").
+		Update(update, "minimal", "").
+		Eq("id", id.String()).
+		Execute()
+	return err
+}
+
+func (s *supabaseDeliveryStore) InsertDeliveryEvent(event deliveries.DeliveryEvent) error {
+	_, _, err := s.client.From("delivery_events").Insert(event, false, "", "minimal", "").Execute()
+	return err
+}
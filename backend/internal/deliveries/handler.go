@@ -0,0 +1,38 @@
+package deliveries
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (s *Service) HandleSummary(w http.ResponseWriter, r *http.Request) {
+	var contentID *uuid.UUID
+	if raw := r.URL.Query().Get("content_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			http.Error(w, "Invalid content_id", http.StatusBadRequest)
+			return
+		}
+		contentID = &id
+	}
+
+	scheduledFor := r.URL.Query().Get("date")
+	if scheduledFor != "" {
+		if _, err := time.Parse(DateFormat, scheduledFor); err != nil {
+			http.Error(w, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	summaries, err := s.Summaries(contentID, scheduledFor)
+	if err != nil {
+		http.Error(w, "Failed to get delivery summaries", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summaries)
+}
@@ -0,0 +1,325 @@
+package deliveries
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const DateFormat = "2006-01-02"
+
+// Statuses follow Twilio's message status values, apart from pending, which
+// marks a slot claimed for a message that has not been handed to Twilio yet.
+const (
+	StatusPending     = "pending"
+	StatusQueued      = "queued"
+	StatusSent        = "sent"
+	StatusDelivered   = "delivered"
+	StatusRead        = "read"
+	StatusFailed      = "failed"
+	StatusUndelivered = "undelivered"
+)
+
+// successfulStatuses are the statuses that mean a slot has been served and
+// must not be sent again.
+var successfulStatuses = []string{StatusQueued, StatusSent, StatusDelivered, StatusRead}
+
+// statusRank orders the statuses a message moves through. Twilio does not
+// guarantee callback order, so a late "sent" must not overwrite "delivered".
+// Failures are terminal and always win.
+var statusRank = map[string]int{
+	StatusPending:     0,
+	StatusQueued:      1,
+	StatusSent:        2,
+	StatusDelivered:   3,
+	StatusRead:        4,
+	StatusFailed:      5,
+	StatusUndelivered: 5,
+}
+
+// Delivery is one user's message for one broadcast. Deleting the content
+// keeps the delivery, with ContentID set to uuid.Nil, so the user's history
+// still shows it was sent.
+type Delivery struct {
+	ID           uuid.UUID `json:"id"`
+	UserID       uuid.UUID `json:"user_id"`
+	ContentID    uuid.UUID `json:"content_id"`
+	ScheduledFor string    `json:"scheduled_for"`
+	Status       string    `json:"status"`
+	MessageSID   *string   `json:"message_sid"`
+	Error        *string   `json:"error"`
+	ErrorCode    *string   `json:"error_code"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type DeliveryUpsert struct {
+	UserID       uuid.UUID `json:"user_id"`
+	ContentID    uuid.UUID `json:"content_id"`
+	ScheduledFor string    `json:"scheduled_for"`
+	Status       string    `json:"status"`
+	MessageSID   *string   `json:"message_sid"`
+	Error        *string   `json:"error"`
+	ErrorCode    *string   `json:"error_code"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type DeliveryUpdate struct {
+	Status    string    `json:"status"`
+	ErrorCode *string   `json:"error_code"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DeliveryEvent is one status callback received for an outbound message.
+type DeliveryEvent struct {
+	MessageSID string    `json:"message_sid"`
+	Status     string    `json:"status"`
+	ErrorCode  *string   `json:"error_code"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type Summary struct {
+	ContentID    uuid.UUID      `json:"content_id"`
+	ScheduledFor string         `json:"scheduled_for"`
+	Total        int            `json:"total"`
+	ByStatus     map[string]int `json:"by_status"`
+	ErrorCodes   map[string]int `json:"error_codes"`
+}
+
+type Service struct {
+	store DeliveryStore
+}
+
+func NewService(store DeliveryStore) *Service {
+	return &Service{
+		store: store,
+	}
+}
+
+func DateKey(t time.Time) string {
+	return t.Format(DateFormat)
+}
+
+// ServedUserIDs returns the users that have a delivery of any content for the
+// given date, whatever its status. Failed sends count too, so a bad number is
+// not sent to again on every dispatch for the rest of the day.
+func (s *Service) ServedUserIDs(scheduledFor time.Time) (map[uuid.UUID]bool, error) {
+	return s.userIDs(DeliveryFilter{ScheduledFor: DateKey(scheduledFor)})
+}
+
+func (s *Service) userIDs(filter DeliveryFilter) (map[uuid.UUID]bool, error) {
+	delivered, err := s.store.ListDeliveries(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deliveries: %w", err)
+	}
+
+	userIDs := make(map[uuid.UUID]bool, len(delivered))
+	for _, delivery := range delivered {
+		userIDs[delivery.UserID] = true
+	}
+	return userIDs, nil
+}
+
+// Claim records a pending delivery of contentID to userID for the given date
+// before the message is sent. It returns ErrDeliveryExists if the slot
+// already has a delivery, in which case the message must not be sent: it was
+// sent before, or a send was interrupted and may have gone out.
+func (s *Service) Claim(userID, contentID uuid.UUID, scheduledFor time.Time) error {
+	_, err := s.store.InsertDelivery(DeliveryUpsert{
+		UserID:       userID,
+		ContentID:    contentID,
+		ScheduledFor: DateKey(scheduledFor),
+		Status:       StatusPending,
+		UpdatedAt:    time.Now(),
+	})
+	if errors.Is(err, ErrDeliveryExists) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to claim delivery: %w", err)
+	}
+	return nil
+}
+
+// RecordSent records a message Twilio accepted. It starts out queued and is
+// moved along by status callbacks.
+func (s *Service) RecordSent(userID, contentID uuid.UUID, scheduledFor time.Time, messageSID string) error {
+	_, err := s.store.UpsertDelivery(DeliveryUpsert{
+		UserID:       userID,
+		ContentID:    contentID,
+		ScheduledFor: DateKey(scheduledFor),
+		Status:       StatusQueued,
+		MessageSID:   &messageSID,
+		UpdatedAt:    time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record delivery: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) RecordFailed(userID, contentID uuid.UUID, scheduledFor time.Time, sendErr error) error {
+	message := sendErr.Error()
+	_, err := s.store.UpsertDelivery(DeliveryUpsert{
+		UserID:       userID,
+		ContentID:    contentID,
+		ScheduledFor: DateKey(scheduledFor),
+		Status:       StatusFailed,
+		Error:        &message,
+		UpdatedAt:    time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record delivery: %w", err)
+	}
+	return nil
+}
+
+// RecordStatus stores a status callback for messageSID and advances the
+// matching delivery if the new status is further along than the current one.
+// Intermediate statuses such as "accepted" or "sending" are only recorded as
+// events.
+func (s *Service) RecordStatus(messageSID, status, errorCode string) error {
+	var code *string
+	if errorCode != "" {
+		code = &errorCode
+	}
+
+	now := time.Now()
+	if err := s.store.InsertDeliveryEvent(DeliveryEvent{
+		MessageSID: messageSID,
+		Status:     status,
+		ErrorCode:  code,
+		CreatedAt:  now,
+	}); err != nil {
+		return fmt.Errorf("failed to record delivery event: %w", err)
+	}
+
+	rank, ok := statusRank[status]
+	if !ok {
+		return nil
+	}
+
+	found, err := s.store.ListDeliveries(DeliveryFilter{MessageSID: messageSID})
+	if err != nil {
+		return fmt.Errorf("failed to get delivery: %w", err)
+	}
+	if len(found) == 0 {
+		return ErrDeliveryNotFound
+	}
+
+	delivery := found[0]
+	if rank < statusRank[delivery.Status] {
+		return nil
+	}
+	if code == nil {
+		code = delivery.ErrorCode
+	}
+
+	if err := s.store.UpdateDelivery(delivery.ID, DeliveryUpdate{
+		Status:    status,
+		ErrorCode: code,
+		UpdatedAt: now,
+	}); err != nil {
+		return fmt.Errorf("failed to update delivery: %w", err)
+	}
+	return nil
+}
+
+// UserHistory returns a user's deliveries, most recent scheduled date first.
+// A limit of zero returns them all.
+func (s *Service) UserHistory(userID uuid.UUID, limit int) ([]Delivery, error) {
+	found, err := s.store.ListDeliveries(DeliveryFilter{UserID: &userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery history: %w", err)
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].ScheduledFor > found[j].ScheduledFor
+	})
+	if limit > 0 && len(found) > limit {
+		found = found[:limit]
+	}
+	return found, nil
+}
+
+// DeliveriesOn returns a user's deliveries for the given date, most recently
+// created first.
+func (s *Service) DeliveriesOn(userID uuid.UUID, scheduledFor time.Time) ([]Delivery, error) {
+	found, err := s.store.ListDeliveries(DeliveryFilter{UserID: &userID, ScheduledFor: DateKey(scheduledFor)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deliveries: %w", err)
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].CreatedAt.After(found[j].CreatedAt)
+	})
+	return found, nil
+}
+
+// ReceivedHistory returns the deliveries a user was sent successfully, most
+// recent scheduled date first. A limit of zero returns them all.
+func (s *Service) ReceivedHistory(userID uuid.UUID, limit int) ([]Delivery, error) {
+	found, err := s.store.ListDeliveries(DeliveryFilter{UserID: &userID, Statuses: successfulStatuses})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery history: %w", err)
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].ScheduledFor != found[j].ScheduledFor {
+			return found[i].ScheduledFor > found[j].ScheduledFor
+		}
+		return found[i].CreatedAt.After(found[j].CreatedAt)
+	})
+	if limit > 0 && len(found) > limit {
+		found = found[:limit]
+	}
+	return found, nil
+}
+
+// Summaries groups deliveries into one summary per broadcast, that is per
+// (content, scheduled date) pair.
+func (s *Service) Summaries(contentID *uuid.UUID, scheduledFor string) ([]Summary, error) {
+	found, err := s.store.ListDeliveries(DeliveryFilter{
+		ContentID:    contentID,
+		ScheduledFor: scheduledFor,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deliveries: %w", err)
+	}
+
+	type broadcastKey struct {
+		contentID    uuid.UUID
+		scheduledFor string
+	}
+
+	summaries := []Summary{}
+	index := map[broadcastKey]int{}
+	for _, delivery := range found {
+		key := broadcastKey{delivery.ContentID, delivery.ScheduledFor}
+		i, ok := index[key]
+		if !ok {
+			i = len(summaries)
+			index[key] = i
+			summaries = append(summaries, Summary{
+				ContentID:    delivery.ContentID,
+				ScheduledFor: delivery.ScheduledFor,
+				ByStatus:     map[string]int{},
+				ErrorCodes:   map[string]int{},
+			})
+		}
+
+		summaries[i].Total++
+		summaries[i].ByStatus[delivery.Status]++
+		if delivery.ErrorCode != nil {
+			summaries[i].ErrorCodes[*delivery.ErrorCode]++
+		}
+	}
+
+	sort.Slice(summaries, func(a, b int) bool {
+		return summaries[a].ScheduledFor > summaries[b].ScheduledFor
+	})
+	return summaries, nil
+}
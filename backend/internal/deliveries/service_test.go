@@ -0,0 +1,58 @@
+package deliveries_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"novissima/internal/database"
+	"novissima/internal/deliveries"
+)
+
+func TestServedUserIDs(t *testing.T) {
+	service := deliveries.NewService(database.NewMemoryDB().Stores().Deliveries)
+	date := time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC)
+	contentID := uuid.New()
+	sent, failed, undelivered, tomorrow := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	if err := service.RecordSent(sent, contentID, date, "SM1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.RecordFailed(failed, contentID, date, errors.New("invalid number")); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.RecordSent(undelivered, contentID, date, "SM2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.RecordStatus("SM2", deliveries.StatusUndelivered, "30003"); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.RecordSent(tomorrow, contentID, date.AddDate(0, 0, 1), "SM3"); err != nil {
+		t.Fatal(err)
+	}
+
+	served, err := service.ServedUserIDs(date)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		userID uuid.UUID
+		want   bool
+	}{
+		{name: "sent", userID: sent, want: true},
+		{name: "failed", userID: failed, want: true},
+		{name: "undelivered", userID: undelivered, want: true},
+		{name: "sent another day", userID: tomorrow, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if served[tt.userID] != tt.want {
+				t.Errorf("served = %v, want %v", served[tt.userID], tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package deliveries
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrDeliveryNotFound = errors.New("delivery not found")
+	ErrDeliveryExists   = errors.New("a delivery already exists for that slot")
+)
+
+type DeliveryFilter struct {
+	UserID       *uuid.UUID
+	ContentID    *uuid.UUID
+	ScheduledFor string
+	Statuses     []string
+	MessageSID   string
+}
+
+type DeliveryStore interface {
+	// InsertDelivery inserts a row for the delivery's (user, content,
+	// scheduled date) slot, or returns ErrDeliveryExists if it has one.
+	InsertDelivery(delivery DeliveryUpsert) (Delivery, error)
+	// UpsertDelivery inserts or replaces the row for the delivery's
+	// (user, content, scheduled date) slot.
+	UpsertDelivery(delivery DeliveryUpsert) (Delivery, error)
+	ListDeliveries(filter DeliveryFilter) ([]Delivery, error)
+	UpdateDelivery(id uuid.UUID, update DeliveryUpdate) error
+	InsertDeliveryEvent(event DeliveryEvent) error
+}
@@ -0,0 +1,406 @@
+package latin
+
+import (
+	"fmt"
+	"strings"
+)
+
+// formSet holds normalized forms.
+type formSet map[string]bool
+
+func (f formSet) add(words ...string) {
+	for _, word := range words {
+		if form := Normalize(word); form != "" {
+			f[form] = true
+		}
+	}
+}
+
+func (f formSet) addEndings(stem string, endings ...string) {
+	for _, ending := range endings {
+		f.add(stem + ending)
+	}
+}
+
+// Endings after the stem. Nominatives are taken from the headword, as they
+// do not follow from the stem in every declension.
+var (
+	firstDeclension  = []string{"a", "ae", "am", "arum", "is", "as"}
+	secondMasculine  = []string{"i", "o", "um", "orum", "is", "os"}
+	secondNeuter     = []string{"um", "i", "o", "a", "orum", "is"}
+	thirdDeclension  = []string{"is", "i", "em", "e", "es", "um", "ibus"}
+	thirdNeuter      = []string{"is", "i", "e", "a", "um", "ibus"}
+	thirdIStem       = []string{"ium", "ia"}
+	fourthDeclension = []string{"us", "ui", "um", "u", "uum", "ibus"}
+	fourthNeuter     = []string{"u", "us", "ua", "uum", "ibus"}
+	fifthDeclension  = []string{"es", "ei", "em", "e", "erum", "ebus"}
+
+	firstSecondAdjective = []string{"us", "a", "um", "i", "ae", "o", "am", "e", "orum", "arum", "is", "os", "as"}
+	thirdAdjective       = []string{"is", "i", "em", "e", "es", "ia", "ium", "ibus"}
+	comparative          = []string{"ior", "ius", "ioris", "iori", "iorem", "iore", "iores", "iora", "iorum", "ioribus"}
+
+	activeEndings  = []string{"m", "s", "t", "mus", "tis", "nt"}
+	passiveEndings = []string{"r", "ris", "tur", "mur", "mini", "ntur"}
+	perfectEndings = []string{
+		"i", "isti", "it", "imus", "istis", "erunt", "ere",
+		"eram", "eras", "erat", "eramus", "eratis", "erant",
+		"ero", "eris", "erit", "erimus", "eritis", "erint",
+		"erim", "issem", "isses", "isset", "issemus", "issetis", "issent", "isse",
+	}
+)
+
+// conjugation describes the present system of a verb conjugation as
+// endings after the present stem.
+type conjugation struct {
+	present, presentPassive []string
+	imperfect               string
+	future                  string
+	// futureInE is set for the conjugations with a future in -am, -es, -et
+	// rather than -bo, -bis, -bit.
+	futureInE                     bool
+	subjunctive                   string
+	imperative                    []string
+	infinitive, infinitivePassive string
+	participle, participleStem    string
+	gerund                        string
+}
+
+var conjugations = map[string]conjugation{
+	"v1": {
+		present:           []string{"o", "as", "at", "amus", "atis", "ant"},
+		presentPassive:    []string{"or", "aris", "atur", "amur", "amini", "antur"},
+		imperfect:         "aba",
+		future:            "ab",
+		subjunctive:       "e",
+		imperative:        []string{"a", "ate", "ato", "atote", "anto"},
+		infinitive:        "are",
+		infinitivePassive: "ari",
+		participle:        "ans",
+		participleStem:    "ant",
+		gerund:            "and",
+	},
+	"v2": {
+		present:           []string{"eo", "es", "et", "emus", "etis", "ent"},
+		presentPassive:    []string{"eor", "eris", "etur", "emur", "emini", "entur"},
+		imperfect:         "eba",
+		future:            "eb",
+		subjunctive:       "ea",
+		imperative:        []string{"e", "ete", "eto", "etote", "ento"},
+		infinitive:        "ere",
+		infinitivePassive: "eri",
+		participle:        "ens",
+		participleStem:    "ent",
+		gerund:            "end",
+	},
+	"v3": {
+		present:           []string{"o", "is", "it", "imus", "itis", "unt"},
+		presentPassive:    []string{"or", "eris", "itur", "imur", "imini", "untur"},
+		imperfect:         "eba",
+		futureInE:         true,
+		subjunctive:       "a",
+		imperative:        []string{"e", "ite", "ito", "itote", "unto"},
+		infinitive:        "ere",
+		infinitivePassive: "i",
+		participle:        "ens",
+		participleStem:    "ent",
+		gerund:            "end",
+	},
+	"v3io": {
+		present:           []string{"io", "is", "it", "imus", "itis", "iunt"},
+		presentPassive:    []string{"ior", "eris", "itur", "imur", "imini", "iuntur"},
+		imperfect:         "ieba",
+		future:            "i",
+		futureInE:         true,
+		subjunctive:       "ia",
+		imperative:        []string{"e", "ite", "ito", "itote", "iunto"},
+		infinitive:        "ere",
+		infinitivePassive: "i",
+		participle:        "iens",
+		participleStem:    "ient",
+		gerund:            "iend",
+	},
+	"v4": {
+		present:           []string{"io", "is", "it", "imus", "itis", "iunt"},
+		presentPassive:    []string{"ior", "iris", "itur", "imur", "imini", "iuntur"},
+		imperfect:         "ieba",
+		future:            "i",
+		futureInE:         true,
+		subjunctive:       "ia",
+		imperative:        []string{"i", "ite", "ito", "itote", "iunto"},
+		infinitive:        "ire",
+		infinitivePassive: "iri",
+		participle:        "iens",
+		participleStem:    "ient",
+		gerund:            "iend",
+	},
+}
+
+var genders = map[string]bool{"m.": true, "f.": true, "n.": true, "c.": true}
+
+// inflect adds the forms of a headword to f.
+func inflect(f formSet, kind string, parts []string) error {
+	for _, part := range parts {
+		if !genders[part] {
+			f.add(strings.Fields(part)[0])
+		}
+	}
+
+	switch {
+	case partsOfSpeech[kind] == "noun":
+		return inflectNoun(f, kind, parts)
+	case kind == "adj12":
+		return inflectFirstSecondAdjective(f, parts)
+	case kind == "adj3":
+		return inflectThirdAdjective(f, parts)
+	case kind == "virr":
+		inflectIrregularVerb(f, parts)
+	case strings.HasPrefix(kind, "v"):
+		conjugationName, deponent := strings.CutSuffix(kind, "-dep")
+		return inflectVerb(f, conjugations[conjugationName], parts, deponent)
+	}
+	// Other words are listed with all their forms.
+	return nil
+}
+
+func cutEnding(word, ending string) (string, error) {
+	stem, ok := strings.CutSuffix(word, ending)
+	if !ok || stem == "" {
+		return "", fmt.Errorf("%q does not end in -%s", word, ending)
+	}
+	return stem, nil
+}
+
+func inflectNoun(f formSet, kind string, parts []string) error {
+	if len(parts) != 3 || !genders[parts[2]] {
+		return fmt.Errorf("want nominative, genitive and gender")
+	}
+	nominative, genitive := parts[0], parts[1]
+	neuter := parts[2] == "n."
+
+	switch kind {
+	case "n1":
+		stem, err := cutEnding(genitive, "ae")
+		if err != nil {
+			return err
+		}
+		f.addEndings(stem, firstDeclension...)
+	case "n2":
+		stem, err := cutEnding(genitive, "i")
+		if err != nil {
+			return err
+		}
+		if neuter {
+			f.addEndings(stem, secondNeuter...)
+			break
+		}
+		f.addEndings(stem, secondMasculine...)
+		if strings.HasSuffix(nominative, "us") && !strings.HasSuffix(nominative, "ius") {
+			f.addEndings(stem, "e")
+		}
+	case "n3":
+		stem, err := cutEnding(genitive, "is")
+		if err != nil {
+			return err
+		}
+		if neuter {
+			f.addEndings(stem, thirdNeuter...)
+		} else {
+			f.addEndings(stem, thirdDeclension...)
+		}
+		if isIStem(nominative, genitive, stem, neuter) {
+			f.addEndings(stem, thirdIStem...)
+		}
+	case "n4":
+		stem, err := cutEnding(genitive, "us")
+		if err != nil {
+			return err
+		}
+		if neuter {
+			f.addEndings(stem, fourthNeuter...)
+		} else {
+			f.addEndings(stem, fourthDeclension...)
+		}
+	case "n5":
+		stem, err := cutEnding(genitive, "ei")
+		if err != nil {
+			return err
+		}
+		f.addEndings(stem, fifthDeclension...)
+	default:
+		return fmt.Errorf("unknown declension %q", kind)
+	}
+	return nil
+}
+
+// isIStem reports whether a third declension noun has a genitive plural in
+// -ium: nouns with as many syllables in the nominative as in the genitive,
+// like ignis, stems ending in two consonants, like mors, mortis, and
+// neuters in -e, -al and -ar.
+func isIStem(nominative, genitive, stem string, neuter bool) bool {
+	if neuter && (strings.HasSuffix(nominative, "e") || strings.HasSuffix(nominative, "al") || strings.HasSuffix(nominative, "ar")) {
+		return true
+	}
+	if !neuter && (strings.HasSuffix(nominative, "is") || strings.HasSuffix(nominative, "es")) && len(nominative) == len(genitive) {
+		return true
+	}
+	if len(stem) < 2 {
+		return false
+	}
+	last, beforeLast := stem[len(stem)-1], stem[len(stem)-2]
+	// A stop followed by r or l, as in patr-, does not count.
+	return !isVowel(last) && !isVowel(beforeLast) && last != 'r' && last != 'l'
+}
+
+func isVowel(c byte) bool {
+	return strings.IndexByte("aeiouy", c) >= 0
+}
+
+func inflectFirstSecondAdjective(f formSet, parts []string) error {
+	if len(parts) != 3 {
+		return fmt.Errorf("want masculine, feminine and neuter")
+	}
+	stem, err := cutEnding(parts[1], "a")
+	if err != nil {
+		return err
+	}
+	f.addEndings(stem, firstSecondAdjective...)
+	f.addEndings(stem, comparative...)
+	if strings.HasSuffix(parts[0], "er") {
+		f.addEndings(parts[0]+"rim", firstSecondAdjective...)
+	} else {
+		f.addEndings(stem+"issim", firstSecondAdjective...)
+	}
+	f.addEndings(stem, "e")
+	return nil
+}
+
+func inflectThirdAdjective(f formSet, parts []string) error {
+	var stem string
+	var err error
+	switch {
+	case len(parts) == 3:
+		// acer, acris, acre
+		stem, err = cutEnding(parts[1], "is")
+	case len(parts) == 2 && strings.HasSuffix(parts[1], "e"):
+		// brevis, breve
+		stem, err = cutEnding(parts[0], "is")
+	case len(parts) == 2:
+		// felix, felicis
+		stem, err = cutEnding(parts[1], "is")
+	default:
+		return fmt.Errorf("want two or three forms")
+	}
+	if err != nil {
+		return err
+	}
+
+	f.addEndings(stem, thirdAdjective...)
+	f.addEndings(stem, comparative...)
+	switch {
+	case strings.HasSuffix(parts[0], "er"):
+		f.addEndings(parts[0]+"rim", firstSecondAdjective...)
+	case strings.HasSuffix(stem, "il"):
+		f.addEndings(stem+"lim", firstSecondAdjective...)
+	default:
+		f.addEndings(stem+"issim", firstSecondAdjective...)
+	}
+	if strings.HasSuffix(stem, "nt") {
+		f.addEndings(stem, "er")
+	} else {
+		f.addEndings(stem, "iter")
+	}
+	return nil
+}
+
+func inflectVerb(f formSet, c conjugation, parts []string, deponent bool) error {
+	if len(parts) < 2 {
+		return fmt.Errorf("want at least the first person and the infinitive")
+	}
+	infinitiveEnding := c.infinitive
+	if deponent {
+		infinitiveEnding = c.infinitivePassive
+	}
+	stem, err := cutEnding(parts[1], infinitiveEnding)
+	if err != nil {
+		return err
+	}
+
+	future, futurePassive := []string{"o", "is", "it", "imus", "itis", "unt"}, []string{"or", "eris", "itur", "imur", "imini", "untur"}
+	if c.futureInE {
+		future, futurePassive = []string{"am", "es", "et", "emus", "etis", "ent"}, []string{"ar", "eris", "etur", "emur", "emini", "entur"}
+	}
+
+	if !deponent {
+		f.addEndings(stem, c.present...)
+		f.addEndings(stem+c.imperfect, activeEndings...)
+		f.addEndings(stem+c.future, future...)
+		f.addEndings(stem+c.subjunctive, activeEndings...)
+		f.addEndings(stem+c.infinitive, activeEndings...)
+		f.addEndings(stem, c.imperative...)
+	}
+	// Deponents have only the passive forms, so the passive is added for
+	// every verb. The passive imperative looks like the active infinitive.
+	f.addEndings(stem, c.presentPassive...)
+	f.addEndings(stem+c.imperfect, passiveEndings...)
+	f.addEndings(stem+c.future, futurePassive...)
+	f.addEndings(stem+c.subjunctive, passiveEndings...)
+	f.addEndings(stem+c.infinitive, passiveEndings...)
+	f.addEndings(stem, c.infinitive, c.infinitivePassive)
+
+	f.addEndings(stem, c.participle)
+	f.addEndings(stem+c.participleStem, thirdAdjective...)
+	f.addEndings(stem+c.gerund, firstSecondAdjective...)
+
+	if deponent {
+		if len(parts) > 2 {
+			return addPerfectParticiple(f, strings.Fields(parts[2])[0])
+		}
+		return nil
+	}
+	if len(parts) > 2 {
+		perfectStem, err := cutEnding(parts[2], "i")
+		if err != nil {
+			return err
+		}
+		f.addEndings(perfectStem, perfectEndings...)
+	}
+	if len(parts) > 3 {
+		supineStem, err := cutEnding(parts[3], "um")
+		if err != nil {
+			return err
+		}
+		return addPerfectParticiple(f, supineStem+"us")
+	}
+	return nil
+}
+
+// addPerfectParticiple adds the perfect participle, as in amatus, and the
+// future participle, as in amaturus.
+func addPerfectParticiple(f formSet, participle string) error {
+	stem, err := cutEnding(participle, "us")
+	if err != nil {
+		return err
+	}
+	f.addEndings(stem, firstSecondAdjective...)
+	f.addEndings(stem+"ur", firstSecondAdjective...)
+	return nil
+}
+
+// inflectIrregularVerb adds the perfect system and participles of an
+// irregular verb. Its present system is listed in the lexicon.
+func inflectIrregularVerb(f formSet, parts []string) {
+	if len(parts) > 2 {
+		if participle, ok := strings.CutSuffix(parts[2], " sum"); ok {
+			addPerfectParticiple(f, participle)
+		} else if perfectStem, err := cutEnding(parts[2], "i"); err == nil {
+			f.addEndings(perfectStem, perfectEndings...)
+		}
+	}
+	if len(parts) > 3 {
+		if stem, ok := strings.CutSuffix(parts[3], "urus"); ok {
+			f.addEndings(stem+"ur", firstSecondAdjective...)
+		} else if stem, ok := strings.CutSuffix(parts[3], "um"); ok {
+			addPerfectParticiple(f, stem+"us")
+		}
+	}
+}
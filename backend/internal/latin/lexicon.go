@@ -0,0 +1,241 @@
+// Package latin looks up Latin words, including inflected forms, in a small
+// bundled glossary.
+package latin
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+//go:embed lexicon.txt
+var lexiconData string
+
+// Entry is a glossary entry.
+type Entry struct {
+	// Headword is the dictionary form, e.g. "mors, mortis, f.".
+	Headword     string
+	Lemma        string
+	PartOfSpeech string
+	Gloss        string
+	key          string
+}
+
+// Enclitic is a particle written joined to the end of a word, like -que.
+type Enclitic struct {
+	Suffix string
+	Gloss  string
+	form   string
+}
+
+var enclitics = []Enclitic{
+	{Suffix: "que", Gloss: "and", form: "que"},
+	{Suffix: "ne", Gloss: "asks a question", form: "ne"},
+	{Suffix: "ve", Gloss: "or", form: "ue"},
+	{Suffix: "cum", Gloss: "with", form: "cum"},
+}
+
+// Match is an entry a word was found under.
+type Match struct {
+	Entry *Entry
+	// Form is the word as looked up, without any enclitic.
+	Form string
+	// Inflected is set when Form is not the entry's lemma.
+	Inflected bool
+	Enclitic  *Enclitic
+}
+
+type Lexicon struct {
+	entries []*Entry
+	forms   map[string][]*Entry
+	// sortedForms lists the keys of forms, for suggestions.
+	sortedForms []string
+}
+
+var partsOfSpeech = map[string]string{
+	"n1":       "noun",
+	"n2":       "noun",
+	"n3":       "noun",
+	"n4":       "noun",
+	"n5":       "noun",
+	"adj12":    "adjective",
+	"adj3":     "adjective",
+	"v1":       "verb",
+	"v2":       "verb",
+	"v3":       "verb",
+	"v3io":     "verb",
+	"v4":       "verb",
+	"virr":     "verb",
+	"v1-dep":   "deponent verb",
+	"v2-dep":   "deponent verb",
+	"v3-dep":   "deponent verb",
+	"v3io-dep": "deponent verb",
+	"v4-dep":   "deponent verb",
+	"pron":     "pronoun",
+	"prep":     "preposition",
+	"adv":      "adverb",
+	"conj":     "conjunction",
+	"interj":   "interjection",
+	"num":      "numeral",
+}
+
+// LoadLexicon parses the bundled glossary and generates the inflected forms
+// of its entries.
+func LoadLexicon() (*Lexicon, error) {
+	return parseLexicon(lexiconData)
+}
+
+func parseLexicon(data string) (*Lexicon, error) {
+	l := &Lexicon{forms: map[string][]*Entry{}}
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entry, forms, err := parseEntry(line)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse lexicon line %d: %w", i+1, err)
+		}
+		l.entries = append(l.entries, entry)
+		for form := range forms {
+			l.forms[form] = append(l.forms[form], entry)
+		}
+	}
+	for form := range l.forms {
+		l.sortedForms = append(l.sortedForms, form)
+	}
+	sort.Strings(l.sortedForms)
+	return l, nil
+}
+
+func parseEntry(line string) (*Entry, formSet, error) {
+	fields := strings.Split(line, "|")
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	if len(fields) < 3 || len(fields) > 4 {
+		return nil, nil, fmt.Errorf("want 3 or 4 fields, got %d", len(fields))
+	}
+
+	kind := fields[0]
+	partOfSpeech, ok := partsOfSpeech[kind]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown kind %q", kind)
+	}
+	var parts []string
+	for _, part := range strings.Split(fields[1], ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 || fields[2] == "" {
+		return nil, nil, fmt.Errorf("missing headword or gloss")
+	}
+
+	forms := formSet{}
+	if err := inflect(forms, kind, parts); err != nil {
+		return nil, nil, fmt.Errorf("failed to inflect %q: %w", fields[1], err)
+	}
+	if len(fields) == 4 {
+		extra, ok := strings.CutPrefix(fields[3], "forms:")
+		if !ok {
+			return nil, nil, fmt.Errorf("fourth field must start with \"forms:\"")
+		}
+		forms.add(strings.Fields(extra)...)
+	}
+
+	return &Entry{
+		Headword:     fields[1],
+		Lemma:        parts[0],
+		PartOfSpeech: partOfSpeech,
+		Gloss:        fields[2],
+		key:          Normalize(parts[0]),
+	}, forms, nil
+}
+
+// Entries returns the glossary entries in the order they are listed.
+func (l *Lexicon) Entries() []*Entry {
+	return l.entries
+}
+
+// Forms returns every form the lexicon knows, normalized and sorted.
+func (l *Lexicon) Forms() []string {
+	return l.sortedForms
+}
+
+// Lookup returns the entries word may be a form of, lemmas first. A word
+// that is not found is tried again without an enclitic, so "mortisque" finds
+// "mors".
+func (l *Lexicon) Lookup(word string) []Match {
+	word = Trim(word)
+	form := Normalize(word)
+	if form == "" {
+		return nil
+	}
+	if matches := l.match(form, word, nil); len(matches) > 0 {
+		return matches
+	}
+
+	for i := range enclitics {
+		enclitic := &enclitics[i]
+		base, ok := strings.CutSuffix(form, enclitic.form)
+		if !ok || len(base) < 2 {
+			continue
+		}
+		baseWord := word[:len(word)-len(enclitic.Suffix)]
+		if !utf8.ValidString(baseWord) || Normalize(baseWord) != base {
+			baseWord = base
+		}
+		if matches := l.match(base, baseWord, enclitic); len(matches) > 0 {
+			return matches
+		}
+	}
+	return nil
+}
+
+func (l *Lexicon) match(form, word string, enclitic *Enclitic) []Match {
+	entries := l.forms[form]
+	matches := make([]Match, 0, len(entries))
+	for _, entry := range entries {
+		matches = append(matches, Match{
+			Entry:     entry,
+			Form:      word,
+			Inflected: entry.key != form,
+			Enclitic:  enclitic,
+		})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return !matches[i].Inflected && matches[j].Inflected
+	})
+	return matches
+}
+
+// Trim lowercases word and strips the punctuation around it.
+func Trim(word string) string {
+	return strings.ToLower(strings.TrimFunc(word, func(r rune) bool { return Normalize(string(r)) == "" }))
+}
+
+// Normalize reduces a word to its lowercase letters without accents, with u
+// for v and i for j, so spelling variants look the same.
+func Normalize(word string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(word)) {
+		switch {
+		case r == 'v':
+			b.WriteRune('u')
+		case r == 'j':
+			b.WriteRune('i')
+		case r == 'æ':
+			b.WriteString("ae")
+		case r == 'œ':
+			b.WriteString("oe")
+		case r >= 'a' && r <= 'z':
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,123 @@
+package latin
+
+import "testing"
+
+func TestLookup(t *testing.T) {
+	lexicon, err := LoadLexicon()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		word          string
+		wantLemma     string
+		wantInflected bool
+		wantEnclitic  string
+	}{
+		{word: "mors", wantLemma: "mors"},
+		{word: "mortis", wantLemma: "mors", wantInflected: true},
+		{word: "Mortem,", wantLemma: "mors", wantInflected: true},
+		{word: "memento", wantLemma: "memini", wantInflected: true},
+		{word: "moriendi", wantLemma: "morior", wantInflected: true},
+		{word: "reverteris", wantLemma: "revertor", wantInflected: true},
+		{word: "pulverem", wantLemma: "pulvis", wantInflected: true},
+		{word: "puluis", wantLemma: "pulvis"},
+		{word: "mortisque", wantLemma: "mors", wantInflected: true, wantEnclitic: "que"},
+		{word: "xyzzy"},
+		{word: "..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			matches := lexicon.Lookup(tt.word)
+			if tt.wantLemma == "" {
+				if len(matches) != 0 {
+					t.Fatalf("Lookup(%q) found %q, want nothing", tt.word, matches[0].Entry.Lemma)
+				}
+				return
+			}
+			if len(matches) == 0 {
+				t.Fatalf("Lookup(%q) found nothing, want %q", tt.word, tt.wantLemma)
+			}
+
+			var match *Match
+			for i := range matches {
+				if matches[i].Entry.Lemma == tt.wantLemma {
+					match = &matches[i]
+					break
+				}
+			}
+			if match == nil {
+				t.Fatalf("Lookup(%q) did not find %q", tt.word, tt.wantLemma)
+			}
+			if match.Inflected != tt.wantInflected {
+				t.Errorf("Inflected = %v, want %v", match.Inflected, tt.wantInflected)
+			}
+			enclitic := ""
+			if match.Enclitic != nil {
+				enclitic = match.Enclitic.Suffix
+			}
+			if enclitic != tt.wantEnclitic {
+				t.Errorf("Enclitic = %q, want %q", enclitic, tt.wantEnclitic)
+			}
+		})
+	}
+}
+
+func TestLookupListsLemmasFirst(t *testing.T) {
+	lexicon, err := LoadLexicon()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, form := range lexicon.Forms() {
+		inflected := false
+		for _, match := range lexicon.Lookup(form) {
+			if !match.Inflected && inflected {
+				t.Errorf("Lookup(%q) lists lemma %q after an inflected match", form, match.Entry.Lemma)
+			}
+			inflected = inflected || match.Inflected
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{word: "Vita", want: "uita"},
+		{word: "iustitia", want: "iustitia"},
+		{word: "justitia", want: "iustitia"},
+		{word: "cælum", want: "caelum"},
+		{word: "pœna", want: "poena"},
+		{word: "mōrs", want: "mors"},
+		{word: "mors!", want: "mors"},
+		{word: "42", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			if got := Normalize(tt.word); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.word, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLexiconErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "too few fields", data: "n2 | dominus, domini, m."},
+		{name: "unknown kind", data: "n9 | dominus, domini, m. | lord"},
+		{name: "missing gloss", data: "n2 | dominus, domini, m. | "},
+		{name: "bad forms field", data: "n2 | dominus, domini, m. | lord | domine"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseLexicon(tt.data); err == nil {
+				t.Errorf("parseLexicon(%q) succeeded, want an error", tt.data)
+			}
+		})
+	}
+}
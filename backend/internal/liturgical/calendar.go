@@ -0,0 +1,192 @@
+package liturgical
+
+import (
+	"time"
+)
+
+type Season string
+
+const (
+	Advent       Season = "advent"
+	Christmas    Season = "christmas"
+	OrdinaryTime Season = "ordinary_time"
+	Lent         Season = "lent"
+	Triduum      Season = "triduum"
+	Easter       Season = "easter"
+)
+
+// Feast names, as used in calendar files. Feasts are listed in order of
+// precedence: when two fall on the same day the earlier one wins.
+const (
+	EasterSunday         = "easter_sunday"
+	GoodFriday           = "good_friday"
+	HolySaturday         = "holy_saturday"
+	HolyThursday         = "holy_thursday"
+	ChristmasDay         = "christmas"
+	Pentecost            = "pentecost"
+	AshWednesday         = "ash_wednesday"
+	PalmSunday           = "palm_sunday"
+	Ascension            = "ascension"
+	Epiphany             = "epiphany"
+	FirstSundayOfAdvent  = "first_sunday_of_advent"
+	ChristTheKing        = "christ_the_king"
+	AllSouls             = "all_souls"
+	AllSaints            = "all_saints"
+	Annunciation         = "annunciation"
+	Assumption           = "assumption"
+	ImmaculateConception = "immaculate_conception"
+	Presentation         = "presentation"
+	BaptismOfTheLord     = "baptism_of_the_lord"
+)
+
+var feastPrecedence = []string{
+	EasterSunday,
+	GoodFriday,
+	HolySaturday,
+	HolyThursday,
+	ChristmasDay,
+	Pentecost,
+	AshWednesday,
+	PalmSunday,
+	Ascension,
+	Epiphany,
+	FirstSundayOfAdvent,
+	ChristTheKing,
+	AllSouls,
+	AllSaints,
+	Annunciation,
+	Assumption,
+	ImmaculateConception,
+	Presentation,
+	BaptismOfTheLord,
+}
+
+type Day struct {
+	Date   time.Time
+	Season Season
+	Feasts []string
+}
+
+// EasterDate computes Easter Sunday in the Gregorian calendar using the
+// anonymous (Meeus/Jones/Butcher) computus.
+func EasterDate(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return date(year, time.Month(month), day)
+}
+
+func AshWednesdayDate(year int) time.Time {
+	return EasterDate(year).AddDate(0, 0, -46)
+}
+
+func PentecostDate(year int) time.Time {
+	return EasterDate(year).AddDate(0, 0, 49)
+}
+
+// AdventStart returns the first Sunday of Advent, the fourth Sunday before
+// Christmas.
+func AdventStart(year int) time.Time {
+	christmas := date(year, time.December, 25)
+	daysBack := int(christmas.Weekday())
+	if daysBack == 0 {
+		daysBack = 7
+	}
+	return christmas.AddDate(0, 0, -daysBack-21)
+}
+
+// BaptismOfTheLordDate returns the Sunday after Epiphany, which closes the
+// Christmas season.
+func BaptismOfTheLordDate(year int) time.Time {
+	epiphany := date(year, time.January, 6)
+	daysAhead := 7 - int(epiphany.Weekday())
+	return epiphany.AddDate(0, 0, daysAhead)
+}
+
+// SeasonOf returns the liturgical season that contains the calendar date of t.
+func SeasonOf(t time.Time) Season {
+	day := date(t.Year(), t.Month(), t.Day())
+	year := day.Year()
+	easter := EasterDate(year)
+
+	switch {
+	case !day.Before(date(year, time.December, 25)):
+		return Christmas
+	case !day.Before(AdventStart(year)):
+		return Advent
+	case !day.After(BaptismOfTheLordDate(year)):
+		return Christmas
+	case day.Before(AshWednesdayDate(year)):
+		return OrdinaryTime
+	case day.Before(easter.AddDate(0, 0, -3)):
+		return Lent
+	case day.Before(easter):
+		return Triduum
+	case !day.After(PentecostDate(year)):
+		return Easter
+	default:
+		return OrdinaryTime
+	}
+}
+
+// FeastsOn returns the feasts celebrated on the calendar date of t, highest
+// precedence first.
+func FeastsOn(t time.Time) []string {
+	day := date(t.Year(), t.Month(), t.Day())
+	year := day.Year()
+	easter := EasterDate(year)
+	advent := AdventStart(year)
+
+	dates := map[string]time.Time{
+		EasterSunday:         easter,
+		GoodFriday:           easter.AddDate(0, 0, -2),
+		HolySaturday:         easter.AddDate(0, 0, -1),
+		HolyThursday:         easter.AddDate(0, 0, -3),
+		ChristmasDay:         date(year, time.December, 25),
+		Pentecost:            PentecostDate(year),
+		AshWednesday:         AshWednesdayDate(year),
+		PalmSunday:           easter.AddDate(0, 0, -7),
+		Ascension:            easter.AddDate(0, 0, 39),
+		Epiphany:             date(year, time.January, 6),
+		FirstSundayOfAdvent:  advent,
+		ChristTheKing:        advent.AddDate(0, 0, -7),
+		AllSouls:             date(year, time.November, 2),
+		AllSaints:            date(year, time.November, 1),
+		Annunciation:         date(year, time.March, 25),
+		Assumption:           date(year, time.August, 15),
+		ImmaculateConception: date(year, time.December, 8),
+		Presentation:         date(year, time.February, 2),
+		BaptismOfTheLord:     BaptismOfTheLordDate(year),
+	}
+
+	var feasts []string
+	for _, feast := range feastPrecedence {
+		if dates[feast].Equal(day) {
+			feasts = append(feasts, feast)
+		}
+	}
+	return feasts
+}
+
+func DayOf(t time.Time) Day {
+	return Day{
+		Date:   date(t.Year(), t.Month(), t.Day()),
+		Season: SeasonOf(t),
+		Feasts: FeastsOn(t),
+	}
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,117 @@
+package liturgical
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestMovableDates(t *testing.T) {
+	tests := []struct {
+		name string
+		got  func(year int) time.Time
+		year int
+		want string
+	}{
+		{name: "easter", got: EasterDate, year: 2024, want: "2024-03-31"},
+		{name: "easter", got: EasterDate, year: 2025, want: "2025-04-20"},
+		{name: "easter", got: EasterDate, year: 2026, want: "2026-04-05"},
+		{name: "easter", got: EasterDate, year: 2000, want: "2000-04-23"},
+		{name: "earliest easter", got: EasterDate, year: 2285, want: "2285-03-22"},
+		{name: "latest easter", got: EasterDate, year: 2038, want: "2038-04-25"},
+		{name: "ash wednesday", got: AshWednesdayDate, year: 2024, want: "2024-02-14"},
+		{name: "ash wednesday", got: AshWednesdayDate, year: 2025, want: "2025-03-05"},
+		{name: "pentecost", got: PentecostDate, year: 2024, want: "2024-05-19"},
+		{name: "pentecost", got: PentecostDate, year: 2025, want: "2025-06-08"},
+		{name: "advent", got: AdventStart, year: 2024, want: "2024-12-01"},
+		{name: "advent", got: AdventStart, year: 2025, want: "2025-11-30"},
+		{name: "advent with christmas on a monday", got: AdventStart, year: 2023, want: "2023-12-03"},
+		{name: "advent with christmas on a sunday", got: AdventStart, year: 2022, want: "2022-11-27"},
+		{name: "baptism of the lord", got: BaptismOfTheLordDate, year: 2025, want: "2025-01-12"},
+		{name: "baptism of the lord after a saturday epiphany", got: BaptismOfTheLordDate, year: 2024, want: "2024-01-07"},
+		{name: "baptism of the lord after a sunday epiphany", got: BaptismOfTheLordDate, year: 2019, want: "2019-01-13"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" "+tt.want, func(t *testing.T) {
+			if got := tt.got(tt.year).Format("2006-01-02"); got != tt.want {
+				t.Errorf("%d = %s, want %s", tt.year, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeasonOf(t *testing.T) {
+	tests := []struct {
+		date string
+		want Season
+	}{
+		{date: "2025-01-01", want: Christmas},
+		{date: "2025-01-12", want: Christmas},
+		{date: "2025-01-13", want: OrdinaryTime},
+		{date: "2025-03-04", want: OrdinaryTime},
+		{date: "2025-03-05", want: Lent},
+		{date: "2025-04-16", want: Lent},
+		{date: "2025-04-17", want: Triduum},
+		{date: "2025-04-19", want: Triduum},
+		{date: "2025-04-20", want: Easter},
+		{date: "2025-06-08", want: Easter},
+		{date: "2025-06-09", want: OrdinaryTime},
+		{date: "2025-11-29", want: OrdinaryTime},
+		{date: "2025-11-30", want: Advent},
+		{date: "2025-12-24", want: Advent},
+		{date: "2025-12-25", want: Christmas},
+		{date: "2025-12-31", want: Christmas},
+	}
+	for _, tt := range tests {
+		t.Run(tt.date, func(t *testing.T) {
+			if got := SeasonOf(day(tt.date)); got != tt.want {
+				t.Errorf("SeasonOf(%s) = %s, want %s", tt.date, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeasonOfUsesTheLocalDate(t *testing.T) {
+	chicago, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Skip(err)
+	}
+	// Still Ash Wednesday's eve in Chicago, though already Ash Wednesday in
+	// UTC.
+	evening := time.Date(2025, 3, 4, 21, 0, 0, 0, chicago)
+	if got := SeasonOf(evening); got != OrdinaryTime {
+		t.Errorf("SeasonOf(%s) = %s, want %s", evening, got, OrdinaryTime)
+	}
+}
+
+func TestFeastsOn(t *testing.T) {
+	tests := []struct {
+		date string
+		want []string
+	}{
+		{date: "2025-04-20", want: []string{EasterSunday}},
+		{date: "2025-04-18", want: []string{GoodFriday}},
+		{date: "2025-05-29", want: []string{Ascension}},
+		{date: "2025-11-23", want: []string{ChristTheKing}},
+		{date: "2025-11-02", want: []string{AllSouls}},
+		{date: "2025-01-12", want: []string{BaptismOfTheLord}},
+		// Holy Thursday outranks the Annunciation when they coincide.
+		{date: "2027-03-25", want: []string{HolyThursday, Annunciation}},
+		{date: "2025-06-09"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.date, func(t *testing.T) {
+			if got := FeastsOn(day(tt.date)); !slices.Equal(got, tt.want) {
+				t.Errorf("FeastsOn(%s) = %v, want %v", tt.date, got, tt.want)
+			}
+		})
+	}
+}
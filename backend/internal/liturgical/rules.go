@@ -0,0 +1,102 @@
+package liturgical
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Rule says what to send on a day: content from Theme, or the specific item
+// ContentID when it is set.
+type Rule struct {
+	Theme     string     `json:"theme,omitempty"`
+	ContentID *uuid.UUID `json:"content_id,omitempty"`
+}
+
+// Rules maps seasons and feasts to rules. Feast rules take precedence over
+// season rules.
+type Rules struct {
+	Seasons map[Season]Rule `json:"seasons"`
+	Feasts  map[string]Rule `json:"feasts"`
+}
+
+// LoadRules reads a calendar file written by the editors. Unknown season or
+// feast names are rejected so typos do not go unnoticed.
+func LoadRules(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read calendar file: %w", err)
+	}
+
+	var rules Rules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse calendar file: %w", err)
+	}
+
+	for season, rule := range rules.Seasons {
+		if !isSeason(season) {
+			return nil, fmt.Errorf("unknown season in calendar file: %s", season)
+		}
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("season %s: %w", season, err)
+		}
+	}
+	for feast, rule := range rules.Feasts {
+		if !isFeast(feast) {
+			return nil, fmt.Errorf("unknown feast in calendar file: %s", feast)
+		}
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("feast %s: %w", feast, err)
+		}
+	}
+
+	return &rules, nil
+}
+
+// RuleFor returns the rule for the calendar date of t, and a short label
+// naming the feast or season it came from.
+func (r *Rules) RuleFor(t time.Time) (Rule, string, bool) {
+	if r == nil {
+		return Rule{}, "", false
+	}
+
+	for _, feast := range FeastsOn(t) {
+		if rule, ok := r.Feasts[feast]; ok {
+			return rule, feast, true
+		}
+	}
+
+	season := SeasonOf(t)
+	if rule, ok := r.Seasons[season]; ok {
+		return rule, string(season), true
+	}
+
+	return Rule{}, "", false
+}
+
+func (r Rule) validate() error {
+	if r.Theme == "" && r.ContentID == nil {
+		return fmt.Errorf("rule needs a theme or a content_id")
+	}
+	return nil
+}
+
+func isSeason(season Season) bool {
+	switch season {
+	case Advent, Christmas, OrdinaryTime, Lent, Triduum, Easter:
+		return true
+	}
+	return false
+}
+
+func isFeast(feast string) bool {
+	for _, known := range feastPrecedence {
+		if feast == known {
+			return true
+		}
+	}
+	return false
+}
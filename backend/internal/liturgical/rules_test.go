@@ -0,0 +1,64 @@
+package liturgical
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRuleFor(t *testing.T) {
+	rules := &Rules{
+		Seasons: map[Season]Rule{Lent: {Theme: "penance"}},
+		Feasts:  map[string]Rule{AshWednesday: {Theme: "death"}},
+	}
+
+	tests := []struct {
+		name      string
+		rules     *Rules
+		date      string
+		wantTheme string
+		wantLabel string
+	}{
+		{name: "feast wins over season", rules: rules, date: "2025-03-05", wantTheme: "death", wantLabel: AshWednesday},
+		{name: "season", rules: rules, date: "2025-03-06", wantTheme: "penance", wantLabel: string(Lent)},
+		{name: "no rule", rules: rules, date: "2025-06-09"},
+		{name: "no calendar", date: "2025-03-05"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, label, ok := tt.rules.RuleFor(day(tt.date))
+			if ok != (tt.wantTheme != "") {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantTheme != "")
+			}
+			if rule.Theme != tt.wantTheme || label != tt.wantLabel {
+				t.Errorf("RuleFor(%s) = %q from %q, want %q from %q", tt.date, rule.Theme, label, tt.wantTheme, tt.wantLabel)
+			}
+		})
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "valid", data: `{"seasons": {"lent": {"theme": "penance"}}, "feasts": {"all_souls": {"theme": "purgatory"}}}`},
+		{name: "unknown season", data: `{"seasons": {"lentt": {"theme": "penance"}}}`, wantErr: true},
+		{name: "unknown feast", data: `{"feasts": {"all_soul": {"theme": "purgatory"}}}`, wantErr: true},
+		{name: "empty rule", data: `{"feasts": {"all_souls": {}}}`, wantErr: true},
+		{name: "invalid json", data: `{`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "calendar.json")
+			if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadRules(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadRules error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
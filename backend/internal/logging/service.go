@@ -2,10 +2,10 @@ package logging
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/supabase-community/supabase-go"
 )
 
 type LogEntry struct {
@@ -17,12 +17,12 @@ type LogEntry struct {
 }
 
 type Service struct {
-	client *supabase.Client
+	store LogStore
 }
 
-func NewService(client *supabase.Client) *Service {
+func NewService(store LogStore) *Service {
 	return &Service{
-		client: client,
+		store: store,
 	}
 }
 
@@ -34,13 +34,21 @@ func (s *Service) LogEvent(eventType, message string, data map[string]interface{
 		}
 	}
 
-	_, _, err := s.client.From("logs").Insert(LogEntry{
+	return s.store.InsertLog(LogEntry{
 		EventType: eventType,
 		Message:   message,
 		Data:      dataJSON,
 		CreatedAt: time.Now(),
-	}, true, "", "", "").Execute()
-	return err
+	})
+}
+
+// ListEvents returns the logged events matching filter, newest first.
+func (s *Service) ListEvents(filter LogFilter) ([]LogEntry, error) {
+	entries, err := s.store.ListLogs(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list log entries: %w", err)
+	}
+	return entries, nil
 }
 
 func (s *Service) LogContentCreated(contentID uuid.UUID, textEnglish string, textLatin string, imageURL string, theme string, imageSource string, textSource string) error {
@@ -55,12 +63,55 @@ func (s *Service) LogContentCreated(contentID uuid.UUID, textEnglish string, tex
 	})
 }
 
+func (s *Service) LogContentUpdated(contentID uuid.UUID, fields []string) error {
+	return s.LogEvent("content_updated", "Content updated", map[string]interface{}{
+		"content_id": contentID,
+		"fields":     fields,
+	})
+}
+
+func (s *Service) LogContentDeleted(contentID uuid.UUID, theme string) error {
+	return s.LogEvent("content_deleted", "Content deleted", map[string]interface{}{
+		"content_id": contentID,
+		"theme":      theme,
+	})
+}
+
+func (s *Service) LogContentStatusChanged(contentID uuid.UUID, from, to, actor, comment string) error {
+	return s.LogEvent("content_status_changed", "Content status changed", map[string]interface{}{
+		"content_id": contentID,
+		"from":       from,
+		"to":         to,
+		"actor":      actor,
+		"comment":    comment,
+	})
+}
+
+func (s *Service) LogOverrideWarning(overrideID, contentID uuid.UUID, date, language, warning string) error {
+	return s.LogEvent("override_warning", "Schedule override cannot serve every subscriber", map[string]interface{}{
+		"override_id": overrideID,
+		"content_id":  contentID,
+		"date":        date,
+		"language":    language,
+		"warning":     warning,
+	})
+}
+
 func (s *Service) LogContentSent(contentID uuid.UUID) error {
 	return s.LogEvent("content_sent", "Content sent", map[string]interface{}{
 		"content_id": contentID,
 	})
 }
 
+func (s *Service) LogTestSent(contentID uuid.UUID, language string, sent, failed []string) error {
+	return s.LogEvent("test_sent", "Content sent to reviewers", map[string]interface{}{
+		"content_id": contentID,
+		"language":   language,
+		"sent":       sent,
+		"failed":     failed,
+	})
+}
+
 func (s *Service) LogUserCreated(userID uuid.UUID, phoneNumber string) error {
 	return s.LogEvent("user_created", "New user created", map[string]interface{}{
 		"user_id":  userID,
@@ -84,5 +135,50 @@ func (s *Service) LogUserLanguageChanged(userID uuid.UUID, language string) erro
 		"user_id":  userID,
 		"language": language,
 	})
-}	
+}
+
+func (s *Service) LogAdminAction(subject, email, role, authMethod, method, path string, status int) error {
+	return s.LogEvent("admin_action", "Admin API request", map[string]interface{}{
+		"subject":     subject,
+		"email":       email,
+		"role":        role,
+		"auth_method": authMethod,
+		"method":      method,
+		"path":        path,
+		"status":      status,
+	})
+}
+
+func (s *Service) LogAuthRejected(method, path, reason, remoteAddr string) error {
+	return s.LogEvent("auth_rejected", "Admin API request rejected", map[string]interface{}{
+		"method":      method,
+		"path":        path,
+		"reason":      reason,
+		"remote_addr": remoteAddr,
+	})
+}
 
+func (s *Service) LogCommandReceived(phoneNumber, command, body, reply string) error {
+	return s.LogEvent("command_received", "Command received", map[string]interface{}{
+		"phone_number": phoneNumber,
+		"command":      command,
+		"body":         body,
+		"reply":        reply,
+	})
+}
+
+func (s *Service) LogWebhookRejected(path, reason, remoteAddr string) error {
+	return s.LogEvent("webhook_rejected", "Twilio webhook rejected", map[string]interface{}{
+		"path":        path,
+		"reason":      reason,
+		"remote_addr": remoteAddr,
+	})
+}
+
+func (s *Service) LogWeeklyDigest(broadcasts, deliveries int, byStatus map[string]int) error {
+	return s.LogEvent("weekly_digest", "Weekly delivery digest", map[string]interface{}{
+		"broadcasts": broadcasts,
+		"deliveries": deliveries,
+		"by_status":  byStatus,
+	})
+}
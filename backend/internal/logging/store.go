@@ -0,0 +1,15 @@
+package logging
+
+// LogFilter selects log entries. PhoneNumber matches the phone_number key
+// in an entry's data.
+type LogFilter struct {
+	EventTypes  []string
+	PhoneNumber string
+	Limit       int
+}
+
+type LogStore interface {
+	InsertLog(entry LogEntry) error
+	// ListLogs returns the entries matching filter, newest first.
+	ListLogs(filter LogFilter) ([]LogEntry, error)
+}
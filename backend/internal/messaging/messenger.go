@@ -0,0 +1,19 @@
+package messaging
+
+// Message is a single outbound WhatsApp message. Variables are the content
+// template variables, left nil when the message cannot use the template;
+// providers send Body and MediaURL whenever they do not use the template.
+// StatusCallback, when set, is the URL the provider should report delivery
+// status changes to.
+type Message struct {
+	To             string
+	Body           string
+	MediaURL       string
+	Variables      map[string]string
+	StatusCallback string
+}
+
+type Messenger interface {
+	// Send delivers msg and returns the provider's message ID.
+	Send(msg Message) (string, error)
+}
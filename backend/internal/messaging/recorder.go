@@ -0,0 +1,79 @@
+package messaging
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// Recorder is a Messenger that delivers nothing. It keeps every message it
+// is asked to send so broadcast logic can be asserted on, and can be told to
+// fail for specific numbers.
+type Recorder struct {
+	mu       sync.Mutex
+	messages []Message
+	failures map[string]error
+	sent     int
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{
+		failures: map[string]error{},
+	}
+}
+
+func (r *Recorder) Send(msg Message) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err, ok := r.failures[msg.To]; ok {
+		return "", err
+	}
+
+	variables := make(map[string]string, len(msg.Variables))
+	for key, value := range msg.Variables {
+		variables[key] = value
+	}
+	msg.Variables = variables
+
+	r.messages = append(r.messages, msg)
+	r.sent++
+	sid := fmt.Sprintf("SMrecorded%06d", r.sent)
+	log.Printf("Recorded message %s to %s", sid, msg.To)
+	return sid, nil
+}
+
+func (r *Recorder) Messages() []Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Message(nil), r.messages...)
+}
+
+func (r *Recorder) MessagesTo(to string) []Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var sent []Message
+	for _, msg := range r.messages {
+		if msg.To == to {
+			sent = append(sent, msg)
+		}
+	}
+	return sent
+}
+
+func (r *Recorder) FailFor(to string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.failures[to] = err
+}
+
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.messages = nil
+	r.failures = map[string]error{}
+}
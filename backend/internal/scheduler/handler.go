@@ -0,0 +1,57 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+type jobStatus struct {
+	Name    string     `json:"name"`
+	Spec    string     `json:"spec"`
+	Enabled bool       `json:"enabled"`
+	NextRun *time.Time `json:"next_run"`
+}
+
+func (s *Service) HandleSchedule(w http.ResponseWriter, r *http.Request) {
+	statuses := []jobStatus{}
+	for _, j := range s.jobs() {
+		status := jobStatus{
+			Name: j.name,
+			Spec: j.spec,
+		}
+		if id, ok := s.entries[j.name]; ok && s.cron != nil {
+			next := s.cron.Entry(id).Next
+			status.Enabled = true
+			status.NextRun = &next
+		}
+		statuses = append(statuses, status)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"enabled":  s.options.Enabled,
+		"timezone": s.options.Location.String(),
+		"jobs":     statuses,
+	})
+}
+
+// HandleRunJob runs the job named in the path right away and responds once it
+// has finished.
+func (s *Service) HandleRunJob(w http.ResponseWriter, r *http.Request) {
+	err := s.RunJob(r.PathValue("job"))
+	switch {
+	case errors.Is(err, ErrUnknownJob):
+		http.Error(w, "Unknown job", http.StatusNotFound)
+		return
+	case errors.Is(err, ErrJobRunning):
+		http.Error(w, "A job is already running", http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "Failed to run job", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
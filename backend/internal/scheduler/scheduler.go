@@ -1,47 +1,295 @@
 package scheduler
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"novissima/internal/content"
+	"novissima/internal/deliveries"
 	"novissima/internal/logging"
 	"novissima/internal/twilio"
+	"novissima/internal/users"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/robfig/cron/v3"
 )
 
+const (
+	JobDailyBroadcast = "daily_broadcast"
+	JobWeeklyDigest   = "weekly_digest"
+)
+
+// Options configures the scheduler. A job whose spec is empty is not
+// registered.
+type Options struct {
+	Enabled            bool
+	Location           *time.Location
+	DailyBroadcastSpec string
+	WeeklyDigestSpec   string
+}
+
+var (
+	ErrUnknownJob = errors.New("unknown job")
+	ErrJobRunning = errors.New("a job is already running")
+)
+
+type job struct {
+	name string
+	spec string
+	run  func()
+}
+
 type Service struct {
 	contentService *content.Service
 	twilioService *twilio.Service
+	userService *users.Service
+	deliveryService *deliveries.Service
 	loggingService *logging.Service
+	options Options
+	cron *cron.Cron
+	entries map[string]cron.EntryID
+	// running keeps a manual run from overlapping a scheduled one, which
+	// could otherwise send the same delivery twice.
+	running sync.Mutex
 }	
 
-func NewService(contentService *content.Service, twilioService *twilio.Service, loggingService *logging.Service) *Service {
+func NewService(contentService *content.Service, twilioService *twilio.Service, userService *users.Service, deliveryService *deliveries.Service, loggingService *logging.Service, options Options) *Service {
+	if options.Location == nil {
+		options.Location = time.UTC
+	}
 	return &Service{
 		contentService: contentService,
 		twilioService: twilioService,
+		userService: userService,
+		deliveryService: deliveryService,
 		loggingService: loggingService,
+		options: options,
+		entries: map[string]cron.EntryID{},
 	}
 }
 
-func (s *Service) Start() {
-	c := cron.New()
+func (s *Service) jobs() []job {
+	return []job{
+		{name: JobDailyBroadcast, spec: s.options.DailyBroadcastSpec, run: s.dispatchDueContent},
+		{name: JobWeeklyDigest, spec: s.options.WeeklyDigestSpec, run: s.sendWeeklyDigest},
+	}
+}
+
+// Start registers every configured job and starts the cron runner. It fails
+// if any spec does not parse, so a bad schedule stops the process at startup
+// rather than silently never firing.
+func (s *Service) Start() error {
+	if !s.options.Enabled {
+		log.Println("Scheduler disabled")
+		return nil
+	}
 	
-	c.AddFunc("*/2 * * * *", func() {
-		log.Println("Starting daily content distribution...")
-		
-		content, err := s.contentService.GetDailyContent()
+	c := cron.New(cron.WithLocation(s.options.Location))
+	
+	for _, j := range s.jobs() {
+		if j.spec == "" {
+			continue
+		}
+		id, err := c.AddFunc(j.spec, s.guarded(j))
 		if err != nil {
-			log.Printf("Error getting daily content: %v", err)
+			return fmt.Errorf("invalid schedule %q for job %s: %w", j.spec, j.name, err)
+		}
+		s.entries[j.name] = id
+	}
+	
+	c.Start()
+	s.cron = c
+	
+	for name, id := range s.entries {
+		log.Printf("Scheduled job %s, next run at %s", name, c.Entry(id).Next.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// RunJob runs the named job now, regardless of its schedule or whether the
+// scheduler is enabled.
+func (s *Service) RunJob(name string) error {
+	for _, j := range s.jobs() {
+		if j.name != name {
+			continue
+		}
+		if !s.running.TryLock() {
+			return ErrJobRunning
+		}
+		defer s.running.Unlock()
+
+		log.Printf("Running job %s on demand", name)
+		j.run()
+		return nil
+	}
+	return ErrUnknownJob
+}
+
+// guarded wraps j for cron, skipping the run if another job is in progress.
+func (s *Service) guarded(j job) func() {
+	return func() {
+		if !s.running.TryLock() {
+			log.Printf("Skipping job %s: another job is still running", j.name)
 			return
 		}
+		defer s.running.Unlock()
+		j.run()
+	}
+}
+
+// dispatchDueContent sends every active user whose preferred local hour has
+// arrived the content for their local date. Users already served for that
+// date are skipped by the delivery ledger, so this can run as often as needed.
+func (s *Service) dispatchDueContent() {
+	now := time.Now()
+	
+	activeUsers, err := s.userService.GetAllActiveUsers()
+	if err != nil {
+		log.Printf("Error getting active users: %v", err)
+		return
+	}
+	
+	dueByDate := map[time.Time][]users.User{}
+	for _, user := range activeUsers {
+		if date, due := user.DueDate(now); due {
+			dueByDate[date] = append(dueByDate[date], user)
+		}
+	}
+	
+	// Earlier dates go first so each date's content is chosen in order.
+	dates := make([]time.Time, 0, len(dueByDate))
+	for date := range dueByDate {
+		dates = append(dates, date)
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	
+	for _, date := range dates {
+		s.dispatchDate(date, dueByDate[date])
+	}
+}
+
+// dispatchDate sends due users their content for date. Overrides can give
+// each language its own item and followed themes narrow the rotation, so
+// users are grouped by the content chosen for their language and themes.
+// Anyone with a delivery for that day is left out, even if an override added
+// since then would now pick something else. That includes failed sends, which
+// are not retried.
+func (s *Service) dispatchDate(date time.Time, due []users.User) {
+	served, err := s.deliveryService.ServedUserIDs(date)
+	if err != nil {
+		log.Printf("Error getting deliveries for %s: %v", date.Format("2006-01-02"), err)
+		return
+	}
+	
+	userIDs := make([]uuid.UUID, 0, len(due))
+	for _, user := range due {
+		userIDs = append(userIDs, user.ID)
+	}
+	follows, err := s.userService.FollowedThemesByUser(userIDs)
+	if err != nil {
+		log.Printf("Error getting followed themes for %s: %v", date.Format("2006-01-02"), err)
+		return
+	}
+	
+	// Content is chosen once per language and set of followed themes.
+	bySelection := map[string]*content.Content{}
+	recipients := map[*content.Content][]users.User{}
+	var order []*content.Content
+	for _, user := range due {
+		if served[user.ID] {
+			continue
+		}
 		
-		err = s.twilioService.SendMessageToAllUsers(content)
+		themes := follows[user.ID]
+		selection := user.Language + "|" + strings.Join(themes, ",")
+		dailyContent, ok := bySelection[selection]
+		if !ok {
+			dailyContent, err = s.contentService.GetDailyContentFor(date, user.Language, themes)
+			if err != nil {
+				log.Printf("Error getting daily content for %s (%s, themes %v): %v", date.Format("2006-01-02"), user.Language, themes, err)
+			}
+			bySelection[selection] = dailyContent
+		}
+		if dailyContent == nil {
+			continue
+		}
+		
+		// Languages resolving to the same item share one send.
+		for _, seen := range order {
+			if seen.ID == dailyContent.ID {
+				dailyContent = seen
+				break
+			}
+		}
+		if _, ok := recipients[dailyContent]; !ok {
+			order = append(order, dailyContent)
+		}
+		recipients[dailyContent] = append(recipients[dailyContent], user)
+	}
+	
+	for _, dailyContent := range order {
+		if err := s.twilioService.SendMessageToUsers(dailyContent, date, recipients[dailyContent]); err != nil {
+			log.Printf("Failed to send content %s for %s: %v", dailyContent.ID, date.Format("2006-01-02"), err)
+		}
+	}
+}
+
+// sendWeeklyDigest sends delivery totals for the seven days before today to
+// the reviewer numbers, and records them in the event log.
+func (s *Service) sendWeeklyDigest() {
+	today := time.Now().In(s.options.Location)
+	
+	byStatus := map[string]int{}
+	broadcasts := 0
+	total := 0
+	for i := 7; i >= 1; i-- {
+		date := deliveries.DateKey(today.AddDate(0, 0, -i))
+		summaries, err := s.deliveryService.Summaries(nil, date)
 		if err != nil {
-			log.Printf("Failed to send daily content: %v", err)
+			log.Printf("Error getting delivery summaries for %s: %v", date, err)
 			return
 		}
-	})
+		for _, summary := range summaries {
+			broadcasts++
+			total += summary.Total
+			for status, count := range summary.ByStatus {
+				byStatus[status] += count
+			}
+		}
+	}
 	
-	c.Start()
-	log.Println("Scheduler started - daily content will be sent at 8 AM")
+	log.Printf("Weekly digest: %d broadcasts, %d deliveries, by status %v", broadcasts, total, byStatus)
+	if err := s.loggingService.LogWeeklyDigest(broadcasts, total, byStatus); err != nil {
+		log.Printf("Error logging weekly digest: %v", err)
+	}
+
+	message := formatWeeklyDigest(today.AddDate(0, 0, -7), today.AddDate(0, 0, -1), broadcasts, total, byStatus)
+	reached, err := s.twilioService.NotifyReviewers(message)
+	if err != nil {
+		log.Printf("Weekly digest not sent: %v", err)
+		return
+	}
+	log.Printf("Weekly digest sent to %d reviewers", reached)
+}
+
+// formatWeeklyDigest renders the digest for the days from to through, with
+// statuses in alphabetical order.
+func formatWeeklyDigest(from, through time.Time, broadcasts, deliveries int, byStatus map[string]int) string {
+	statuses := make([]string, 0, len(byStatus))
+	for status := range byStatus {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Weekly digest, %s to %s\n", from.Format("2 Jan"), through.Format("2 Jan"))
+	fmt.Fprintf(&b, "%d broadcasts, %d deliveries", broadcasts, deliveries)
+	for _, status := range statuses {
+		fmt.Fprintf(&b, "\n• %s: %d", status, byStatus[status])
+	}
+	return b.String()
 }
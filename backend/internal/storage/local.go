@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"novissima/internal/content"
+)
+
+// LocalStore writes images under a directory on disk. The files are served
+// by Handler, which should be mounted at the path part of baseURL.
+type LocalStore struct {
+	dir     string
+	baseURL string
+}
+
+func NewLocalStore(dir, baseURL string) *LocalStore {
+	return &LocalStore{
+		dir:     dir,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+func (s *LocalStore) Upload(name string, data []byte, contentType string) (string, error) {
+	fullPath, err := s.resolve(name)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create image directory: %w", err)
+	}
+	if err := os.WriteFile(fullPath, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write image: %w", err)
+	}
+
+	return s.baseURL + "/" + name, nil
+}
+
+func (s *LocalStore) Delete(imageURL string) error {
+	name, found := strings.CutPrefix(imageURL, s.baseURL+"/")
+	if !found {
+		return content.ErrImageNotManaged
+	}
+
+	fullPath, err := s.resolve(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete image: %w", err)
+	}
+	return nil
+}
+
+func (s *LocalStore) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		fullPath, err := s.resolve(r.URL.Path)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		info, err := os.Stat(fullPath)
+		if err != nil || info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+
+		http.ServeFile(w, r, fullPath)
+	})
+}
+
+func (s *LocalStore) resolve(name string) (string, error) {
+	cleaned := path.Clean("/" + name)
+	if cleaned == "/" {
+		return "", fmt.Errorf("invalid image path: %q", name)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(cleaned)), nil
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"novissima/internal/content"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Store uploads to any S3-compatible object store (AWS S3, MinIO, R2...).
+// The bucket must allow public reads, since the returned URLs are handed to
+// Twilio as-is.
+type S3Store struct {
+	client        *minio.Client
+	bucket        string
+	publicBaseURL string
+}
+
+type S3Options struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	UseSSL          bool
+	PublicBaseURL   string
+}
+
+func NewS3Store(opts S3Options) (*S3Store, error) {
+	client, err := minio.New(opts.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(opts.AccessKeyID, opts.SecretAccessKey, ""),
+		Secure: opts.UseSSL,
+		Region: opts.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create s3 client: %w", err)
+	}
+
+	publicBaseURL := opts.PublicBaseURL
+	if publicBaseURL == "" {
+		scheme := "http"
+		if opts.UseSSL {
+			scheme = "https"
+		}
+		publicBaseURL = fmt.Sprintf("%s://%s/%s", scheme, opts.Endpoint, opts.Bucket)
+	}
+
+	return &S3Store{
+		client:        client,
+		bucket:        opts.Bucket,
+		publicBaseURL: strings.TrimSuffix(publicBaseURL, "/"),
+	}, nil
+}
+
+func (s *S3Store) Upload(path string, data []byte, contentType string) (string, error) {
+	_, err := s.client.PutObject(
+		context.Background(),
+		s.bucket,
+		path,
+		bytes.NewReader(data),
+		int64(len(data)),
+		minio.PutObjectOptions{ContentType: contentType},
+	)
+	if err != nil {
+		return "", err
+	}
+	return s.publicBaseURL + "/" + path, nil
+}
+
+func (s *S3Store) Delete(imageURL string) error {
+	key, found := strings.CutPrefix(imageURL, s.publicBaseURL+"/")
+	if !found || key == "" {
+		return content.ErrImageNotManaged
+	}
+	return s.client.RemoveObject(context.Background(), s.bucket, key, minio.RemoveObjectOptions{})
+}
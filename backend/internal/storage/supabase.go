@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"bytes"
+	"strings"
+
+	"novissima/internal/content"
+
+	storage_go "github.com/supabase-community/storage-go"
+)
+
+type SupabaseStore struct {
+	client *storage_go.Client
+	bucket string
+}
+
+func NewSupabaseStore(client *storage_go.Client, bucket string) *SupabaseStore {
+	return &SupabaseStore{
+		client: client,
+		bucket: bucket,
+	}
+}
+
+func (s *SupabaseStore) Upload(path string, data []byte, contentType string) (string, error) {
+	fileOptions := storage_go.FileOptions{
+		ContentType: &contentType,
+	}
+	if _, err := s.client.UploadFile(s.bucket, path, bytes.NewReader(data), fileOptions); err != nil {
+		return "", err
+	}
+	return s.client.GetPublicUrl(s.bucket, path).SignedURL, nil
+}
+
+func (s *SupabaseStore) Delete(imageURL string) error {
+	_, objectPath, found := strings.Cut(imageURL, "/object/public/"+s.bucket+"/")
+	if !found || objectPath == "" {
+		return content.ErrImageNotManaged
+	}
+	_, err := s.client.RemoveFile(s.bucket, []string{objectPath})
+	return err
+}
@@ -1,20 +1,72 @@
 package twilio
 
 import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"novissima/internal/messaging"
+
 	"github.com/twilio/twilio-go"
+	twilioApi "github.com/twilio/twilio-go/rest/api/v2010"
 )
 
 type Client struct {
-	twilioClient *twilio.RestClient
-	phoneNumber  string
+	twilioClient        *twilio.RestClient
+	phoneNumber         string
+	contentSid          string
+	messagingServiceSid string
 }
 
-func NewClient(accountSid, authToken, phoneNumber string) *Client {
+func NewClient(accountSid, authToken, phoneNumber, contentSid, messagingServiceSid string) *Client {
 	return &Client{
 		twilioClient: twilio.NewRestClientWithParams(twilio.ClientParams{
 			Username: accountSid,
 			Password: authToken,
 		}),
-		phoneNumber: phoneNumber,
+		phoneNumber:         phoneNumber,
+		contentSid:          contentSid,
+		messagingServiceSid: messagingServiceSid,
+	}
+}
+
+// Send implements messaging.Messenger. When a content template is configured
+// and the message carries template variables the template is used, otherwise
+// the body and media are sent as a free-form message.
+func (c *Client) Send(msg messaging.Message) (string, error) {
+	params := &twilioApi.CreateMessageParams{}
+	params.SetTo("whatsapp:" + msg.To)
+	params.SetFrom("whatsapp:" + c.phoneNumber)
+	if c.messagingServiceSid != "" {
+		params.SetMessagingServiceSid(c.messagingServiceSid)
+	}
+	if msg.StatusCallback != "" {
+		params.SetStatusCallback(msg.StatusCallback)
+	}
+
+	if c.contentSid != "" && len(msg.Variables) > 0 {
+		variablesJSON, err := json.Marshal(msg.Variables)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode content variables: %w", err)
+		}
+		params.SetContentSid(c.contentSid)
+		params.SetContentVariables(string(variablesJSON))
+	} else {
+		params.SetBody(msg.Body)
+		if msg.MediaURL != "" {
+			params.SetMediaUrl([]string{msg.MediaURL})
+		}
+	}
+
+	twilioMessage, err := c.twilioClient.Api.CreateMessage(params)
+	if err != nil {
+		return "", err
+	}
+	jsonMsg, _ := json.MarshalIndent(twilioMessage, "", "  ")
+	log.Printf("Twilio message: %s", jsonMsg)
+
+	if twilioMessage.Sid == nil {
+		return "", nil
 	}
+	return *twilioMessage.Sid, nil
 }
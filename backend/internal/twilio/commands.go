@@ -0,0 +1,269 @@
+package twilio
+
+import (
+	"fmt"
+	"strings"
+)
+
+// command is something a subscriber can text the bot. Help is keyed by
+// language; English is used when a language has no entry.
+type command struct {
+	name    string
+	aliases []string
+	// args is the argument spec shown in help and usage messages, e.g.
+	// "[en|la|both]". Commands without arguments ignore trailing words, so
+	// "stop please" still stops.
+	args    string
+	minArgs int
+	maxArgs int
+	help    map[string]string
+	handler func(cleanNumber string, args []string) string
+}
+
+func (c *command) usage() string {
+	if c.args == "" {
+		return c.name
+	}
+	return c.name + " " + c.args
+}
+
+type commandRegistry struct {
+	commands []*command
+	byName   map[string]*command
+}
+
+func newCommandRegistry(commands ...*command) *commandRegistry {
+	r := &commandRegistry{byName: map[string]*command{}}
+	for _, c := range commands {
+		r.commands = append(r.commands, c)
+		for _, name := range append([]string{c.name}, c.aliases...) {
+			if _, exists := r.byName[name]; exists {
+				panic(fmt.Sprintf("twilio: command name %q registered twice", name))
+			}
+			r.byName[name] = c
+		}
+	}
+	return r
+}
+
+func (r *commandRegistry) lookup(name string) (*command, bool) {
+	c, ok := r.byName[strings.ToLower(name)]
+	return c, ok
+}
+
+// suggest returns the command whose name or alias is closest to name, if
+// it is close enough to be a typo.
+func (r *commandRegistry) suggest(name string) (*command, bool) {
+	name = strings.ToLower(name)
+	var best *command
+	bestDistance := 0
+	for alias, c := range r.byName {
+		if len(alias) < 3 {
+			// Anything is one edit away from "?".
+			continue
+		}
+		limit := 1
+		if len(alias) > 4 {
+			limit = 2
+		}
+		distance := editDistance(name, alias)
+		if distance > limit {
+			continue
+		}
+		if best == nil || distance < bestDistance || (distance == bestDistance && c.name < best.name) {
+			best, bestDistance = c, distance
+		}
+	}
+	return best, best != nil
+}
+
+// editDistance is the Damerau-Levenshtein distance between a and b, so a
+// swap of two neighbouring letters counts as one edit.
+func editDistance(a, b string) int {
+	x, y := []rune(a), []rune(b)
+	d := make([][]int, len(x)+1)
+	for i := range d {
+		d[i] = make([]int, len(y)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(x); i++ {
+		for j := 1; j <= len(y); j++ {
+			cost := 1
+			if x[i-1] == y[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && x[i-1] == y[j-2] && x[i-2] == y[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(x)][len(y)]
+}
+
+func (s *Service) registerCommands() *commandRegistry {
+	return newCommandRegistry(
+		&command{
+			name:    "start",
+			aliases: []string{"subscribe"},
+			help: map[string]string{
+				"en": "Start receiving daily content (registers you if needed)",
+				"la": "Nuntios cotidianos accipere incipe",
+			},
+			handler: func(cleanNumber string, args []string) string { return s.startSubscription(cleanNumber) },
+		},
+		&command{
+			name:    "stop",
+			aliases: []string{"unsubscribe"},
+			help: map[string]string{
+				"en": "Stop receiving daily content",
+				"la": "Nuntios cotidianos accipere desine",
+			},
+			handler: func(cleanNumber string, args []string) string { return s.stopSubscription(cleanNumber) },
+		},
+		&command{
+			name:    "help",
+			aliases: []string{"?", "commands", "auxilium"},
+			help: map[string]string{
+				"en": "Show this help message",
+				"la": "Hunc nuntium auxilii ostende",
+			},
+			handler: func(cleanNumber string, args []string) string { return s.getHelp(cleanNumber) },
+		},
+		&command{
+			name:    "lang",
+			aliases: []string{"language", "lingua"},
+			args:    "[en|la|both]",
+			minArgs: 1,
+			maxArgs: 1,
+			help: map[string]string{
+				"en": "Set language (English/Latin/Both)",
+				"la": "Linguam elige (Anglicam/Latinam/utramque)",
+			},
+			handler: s.setLanguage,
+		},
+		&command{
+			name:    "today",
+			aliases: []string{"hodie"},
+			help: map[string]string{
+				"en": fmt.Sprintf("Send today's text again (up to %d times a day)", todayLimit),
+				"la": "Textum hodiernum iterum mitte",
+			},
+			handler: func(cleanNumber string, args []string) string { return s.sendToday(cleanNumber) },
+		},
+		&command{
+			name:    "history",
+			aliases: []string{"archive"},
+			args:    "[n]",
+			maxArgs: 1,
+			help: map[string]string{
+				"en": fmt.Sprintf("List the last texts you were sent (%d unless you give a number)", defaultHistoryItems),
+				"la": "Textus nuper acceptos ostende",
+			},
+			handler: s.showHistory,
+		},
+		&command{
+			name:    "show",
+			args:    "[n]",
+			minArgs: 1,
+			maxArgs: 1,
+			help: map[string]string{
+				"en": `Send text number n from your history again, e.g. "show 2"`,
+				"la": `Textum ex historia iterum mitte, e.g. "show 2"`,
+			},
+			handler: s.showReceived,
+		},
+		&command{
+			name:    "define",
+			aliases: []string{"quid"},
+			args:    "[word]",
+			minArgs: 1,
+			maxArgs: maxDefineWords,
+			help: map[string]string{
+				"en": fmt.Sprintf(`Look up Latin words, e.g. "define mortis" (up to %d at a time)`, maxDefineWords),
+				"la": `Verbum Latinum quaere, e.g. "define mortis"`,
+			},
+			handler: s.define,
+		},
+		&command{
+			name:    "themes",
+			aliases: []string{"topics"},
+			help: map[string]string{
+				"en": "List the themes you can follow",
+				"la": "Argumenta quae sequi potes ostende",
+			},
+			handler: func(cleanNumber string, args []string) string { return s.listThemes(cleanNumber) },
+		},
+		&command{
+			name:    "follow",
+			args:    "[theme]",
+			minArgs: 1,
+			maxArgs: 1,
+			help: map[string]string{
+				"en": "Get daily texts from a theme (you get every theme until you follow one)",
+				"la": "Textus cotidianos ex argumento accipe",
+			},
+			handler: s.followTheme,
+		},
+		&command{
+			name:    "unfollow",
+			args:    "[theme]",
+			minArgs: 1,
+			maxArgs: 1,
+			help: map[string]string{
+				"en": "Stop getting texts from a theme",
+				"la": "Textus ex argumento accipere desine",
+			},
+			handler: s.unfollowTheme,
+		},
+		&command{
+			name: "status",
+			help: map[string]string{
+				"en": "Get the status of your subscription",
+				"la": "Statum subscriptionis tuae ostende",
+			},
+			handler: func(cleanNumber string, args []string) string { return s.getStatus(cleanNumber) },
+		},
+		&command{
+			name:    "time",
+			aliases: []string{"hour", "hora"},
+			args:    "[hour] [timezone]",
+			maxArgs: 2,
+			help: map[string]string{
+				"en": `Set when you receive the daily text, e.g. "time 7 America/Chicago"`,
+				"la": `Horam nuntii cotidiani elige, e.g. "time 7 Europe/Rome"`,
+			},
+			handler: s.setDeliveryTime,
+		},
+	)
+}
+
+// helpHeaders are the lines around the command list in each help language.
+var helpHeaders = map[string][2]string{
+	"en": {"Available commands:", `Example: "start" or "lang en" or "status"`},
+	"la": {"Mandata:", `Exemplum: "start" aut "lang la" aut "status"`},
+}
+
+// helpText lists the registered commands for language, "en" or "la".
+func (r *commandRegistry) helpText(language string) string {
+	headers, ok := helpHeaders[language]
+	if !ok {
+		language = "en"
+		headers = helpHeaders[language]
+	}
+
+	var b strings.Builder
+	b.WriteString(headers[0] + "\n")
+	for _, c := range r.commands {
+		help, ok := c.help[language]
+		if !ok {
+			help = c.help["en"]
+		}
+		b.WriteString("• " + c.usage() + " - " + help + "\n")
+	}
+	b.WriteString("\n" + headers[1])
+	return b.String()
+}
@@ -0,0 +1,105 @@
+package twilio
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCommandRegistryLookup(t *testing.T) {
+	registry := newTestEnv(t).service.commands
+
+	tests := []struct {
+		name     string
+		input    string
+		wantName string
+	}{
+		{name: "name", input: "stop", wantName: "stop"},
+		{name: "alias", input: "unsubscribe", wantName: "stop"},
+		{name: "latin alias", input: "hodie", wantName: "today"},
+		{name: "case insensitive", input: "HeLp", wantName: "help"},
+		{name: "unknown", input: "frobnicate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, ok := registry.lookup(tt.input)
+			if tt.wantName == "" {
+				if ok {
+					t.Fatalf("lookup(%q) = %q, want no command", tt.input, c.name)
+				}
+				return
+			}
+			if !ok || c.name != tt.wantName {
+				t.Fatalf("lookup(%q) = %v, %v, want %q", tt.input, c, ok, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestCommandRegistrySuggest(t *testing.T) {
+	registry := newTestEnv(t).service.commands
+
+	tests := []struct {
+		input    string
+		wantName string
+	}{
+		{input: "stpo", wantName: "stop"},
+		{input: "hsitory", wantName: "history"},
+		{input: "defin", wantName: "define"},
+		{input: "tody", wantName: "today"},
+		// Short aliases like "?" would match anything one letter long.
+		{input: "x"},
+		{input: "frobnicate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			c, ok := registry.suggest(tt.input)
+			if tt.wantName == "" {
+				if ok {
+					t.Fatalf("suggest(%q) = %q, want no suggestion", tt.input, c.name)
+				}
+				return
+			}
+			if !ok || c.name != tt.wantName {
+				t.Fatalf("suggest(%q) = %v, %v, want %q", tt.input, c, ok, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestNewCommandRegistryRejectsDuplicateNames(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("registering a name twice did not panic")
+		}
+	}()
+	newCommandRegistry(
+		&command{name: "stop"},
+		&command{name: "halt", aliases: []string{"stop"}},
+	)
+}
+
+func TestProcessMessageArguments(t *testing.T) {
+	env := newTestEnv(t)
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "empty message", body: "  ", want: "Hello! Text 'help' to see available commands."},
+		{name: "typo", body: "stpo", want: "Unknown command 'stpo', did you mean 'stop'?"},
+		{name: "unknown command", body: "frobnicate", want: "Unknown command. Text 'help'"},
+		{name: "missing argument", body: "lang", want: "Usage: lang [en|la|both]"},
+		{name: "too many arguments", body: "lang en la", want: "Usage: lang [en|la|both]"},
+		{name: "too many words", body: "define a b c d", want: "Usage: define [word]"},
+		{name: "trailing words are ignored without args", body: "help me", want: "start"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := env.service.processMessage("whatsapp:+15550000001", tt.body)
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("processMessage(%q) = %q, want it to contain %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package twilio
+
+import (
+	"fmt"
+	"strings"
+
+	"novissima/internal/latin"
+)
+
+const (
+	maxDefineWords = 3
+	// maxDefinitions is how many entries are shown for a word that could be
+	// a form of several, which keeps replies to a screen or so.
+	maxDefinitions = 3
+)
+
+// define looks up each word in the Latin glossary.
+func (s *Service) define(cleanNumber string, args []string) string {
+	replies := make([]string, 0, len(args))
+	for _, word := range args {
+		replies = append(replies, s.defineWord(word))
+	}
+	return strings.Join(replies, "\n\n")
+}
+
+func (s *Service) defineWord(word string) string {
+	word = latin.Trim(word)
+	if latin.Normalize(word) == "" {
+		return `Please send a Latin word, e.g. "define mortis"`
+	}
+	matches := s.lexicon.Lookup(word)
+	if len(matches) == 0 {
+		suggestion, ok := s.suggestForm(word)
+		if !ok {
+			return fmt.Sprintf("*%s* is not in the glossary.", word)
+		}
+		// Forms are stored normalized, so name the entry rather than the
+		// form in case the form is spelled with u for v.
+		match := s.lexicon.Lookup(suggestion)[0]
+		if match.Inflected {
+			return fmt.Sprintf("*%s* is not in the glossary. Did you mean a form of *%s*?", word, match.Entry.Headword)
+		}
+		return fmt.Sprintf("*%s* is not in the glossary. Did you mean *%s*?", word, match.Entry.Lemma)
+	}
+
+	var lines []string
+	if enclitic := matches[0].Enclitic; enclitic != nil {
+		lines = append(lines, fmt.Sprintf("*%s* is *%s* + -%s (%s).", word, matches[0].Form, enclitic.Suffix, enclitic.Gloss))
+	}
+	for _, match := range matches[:min(len(matches), maxDefinitions)] {
+		entry := match.Entry
+		definition := fmt.Sprintf("*%s* (%s): %s", entry.Headword, entry.PartOfSpeech, entry.Gloss)
+		if match.Inflected {
+			definition = fmt.Sprintf("*%s* is a form of %s", match.Form, definition)
+		}
+		lines = append(lines, definition)
+	}
+	if more := len(matches) - maxDefinitions; more > 0 {
+		lines = append(lines, fmt.Sprintf("(and %d more)", more))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// suggestForm returns the known form closest to word, if it is close enough
+// to be a typo.
+func (s *Service) suggestForm(word string) (string, bool) {
+	normalized := latin.Normalize(word)
+	limit := 1
+	if len(normalized) > 4 {
+		limit = 2
+	}
+
+	// Lemmas come first so they win ties.
+	candidates := make([]string, 0, len(s.lexicon.Entries())+len(s.lexicon.Forms()))
+	for _, entry := range s.lexicon.Entries() {
+		candidates = append(candidates, latin.Normalize(entry.Lemma))
+	}
+	candidates = append(candidates, s.lexicon.Forms()...)
+
+	best, bestDistance := "", 0
+	for _, form := range candidates {
+		if abs(len(form)-len(normalized)) > limit {
+			continue
+		}
+		distance := editDistance(normalized, form)
+		if distance <= limit && (best == "" || distance < bestDistance) {
+			best, bestDistance = form, distance
+		}
+	}
+	return best, best != ""
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
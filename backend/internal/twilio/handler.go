@@ -1,8 +1,17 @@
 package twilio
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"net/http"
+	"novissima/internal/content"
+	"novissima/internal/deliveries"
+	"novissima/internal/users"
+	"strings"
+
+	"github.com/google/uuid"
 )
 
 func (s *Service) HandleWebhook(w http.ResponseWriter, r *http.Request) {
@@ -23,5 +32,130 @@ func (s *Service) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	response := s.processMessage(from, body)
+	s.logCommand(from, body, response)
 	s.sendResponse(w, response)
 }
+
+// logCommand records an inbound message and our reply, which is what the
+// admin API shows as a user's command history.
+func (s *Service) logCommand(from, body, reply string) {
+	command := ""
+	if fields := strings.Fields(body); len(fields) > 0 {
+		command = strings.ToLower(fields[0])
+		if registered, ok := s.commands.lookup(command); ok {
+			command = registered.name
+		}
+	}
+
+	phoneNumber := strings.TrimPrefix(from, "whatsapp:")
+	if err := s.loggingService.LogCommandReceived(phoneNumber, command, body, reply); err != nil {
+		log.Printf("Error logging command from %s: %v", phoneNumber, err)
+	}
+}
+
+func (s *Service) HandleStatus(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	messageSID := r.FormValue("MessageSid")
+	status := r.FormValue("MessageStatus")
+	errorCode := r.FormValue("ErrorCode")
+	if messageSID == "" || status == "" {
+		http.Error(w, "MessageSid and MessageStatus are required", http.StatusBadRequest)
+		return
+	}
+
+	err := s.deliveryService.RecordStatus(messageSID, status, errorCode)
+	if errors.Is(err, deliveries.ErrDeliveryNotFound) {
+		log.Printf("Status %s for message %s with no delivery record", status, messageSID)
+	} else if err != nil {
+		log.Printf("Error recording status %s for message %s: %v", status, messageSID, err)
+		http.Error(w, "Failed to record status", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandlePreview serves GET /content/{id}/preview with the message each
+// language setting would receive, or only the one named by ?language=.
+func (s *Service) HandlePreview(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid content id", http.StatusBadRequest)
+		return
+	}
+
+	language := r.URL.Query().Get("language")
+	if language != "" && !users.IsValidLanguage(language) {
+		http.Error(w, "Invalid language, expected en, la or both", http.StatusBadRequest)
+		return
+	}
+
+	c, err := s.contentService.GetContent(id)
+	if errors.Is(err, content.ErrContentNotFound) {
+		http.Error(w, "Content not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to get content", http.StatusInternalServerError)
+		return
+	}
+
+	previews := s.PreviewAll(&c)
+	if language != "" {
+		previews = []MessagePreview{s.Preview(&c, language)}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(previews)
+}
+
+type sendTestRequest struct {
+	Language string `json:"language"`
+}
+
+// HandleSendTest serves POST /content/{id}/send-test, sending the item to the
+// reviewer numbers. The optional JSON body picks the language, "both" by
+// default.
+func (s *Service) HandleSendTest(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid content id", http.StatusBadRequest)
+		return
+	}
+
+	var request sendTestRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if request.Language == "" {
+		request.Language = "both"
+	}
+	if !users.IsValidLanguage(request.Language) {
+		http.Error(w, "Invalid language, expected en, la or both", http.StatusBadRequest)
+		return
+	}
+
+	results, err := s.SendTest(id, request.Language)
+	switch {
+	case errors.Is(err, ErrNoReviewers):
+		http.Error(w, "No reviewer numbers are configured, set REVIEWER_NUMBERS", http.StatusConflict)
+		return
+	case errors.Is(err, content.ErrContentNotFound):
+		http.Error(w, "Content not found", http.StatusNotFound)
+		return
+	case errors.Is(err, ErrNoLatinText):
+		http.Error(w, "Content has no Latin text, Latin subscribers are not sent it", http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, "Failed to send test", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
@@ -0,0 +1,182 @@
+package twilio
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"novissima/internal/content"
+	"novissima/internal/deliveries"
+	"novissima/internal/users"
+)
+
+const (
+	defaultHistoryItems = 5
+	// maxHistoryItems is also how far back "show" can reach.
+	maxHistoryItems = 20
+	excerptLength   = 60
+
+	showLimit  = 5
+	showWindow = 24 * time.Hour
+)
+
+// receivedContent is an item from a user's delivery history, numbered from 1
+// for the most recent.
+type receivedContent struct {
+	Number  int
+	Date    string
+	Content *content.Content
+}
+
+// receivedHistory returns up to limit items the user was sent, newest first.
+// Deliveries outlive the content they sent, which loses its ID when it is
+// deleted, so deleted items are kept with no Content and the numbers match
+// what the user saw.
+func (s *Service) receivedHistory(user *users.User, limit int) ([]receivedContent, error) {
+	delivered, err := s.deliveryService.ReceivedHistory(user.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]receivedContent, 0, len(delivered))
+	for i, delivery := range delivered {
+		item := receivedContent{Number: i + 1, Date: delivery.ScheduledFor}
+		if delivery.ContentID == uuid.Nil {
+			history = append(history, item)
+			continue
+		}
+		c, err := s.contentService.GetContent(delivery.ContentID)
+		if err != nil && !errors.Is(err, content.ErrContentNotFound) {
+			return nil, err
+		}
+		if err == nil {
+			item.Content = &c
+		}
+		history = append(history, item)
+	}
+	return history, nil
+}
+
+// showHistory lists the last items the user was sent with excerpts in their
+// language.
+func (s *Service) showHistory(cleanNumber string, args []string) string {
+	user, err := s.ensureUserExists(cleanNumber)
+	if err != nil {
+		return "Sorry, there was an error getting your history. Please try again later."
+	}
+
+	count := defaultHistoryItems
+	if len(args) > 0 {
+		count, err = strconv.Atoi(args[0])
+		if err != nil || count < 1 {
+			return "Usage: history [n], e.g. \"history 10\""
+		}
+		count = min(count, maxHistoryItems)
+	}
+
+	history, err := s.receivedHistory(user, count)
+	if err != nil {
+		log.Printf("Error getting history for %s: %v", cleanNumber, err)
+		return "Sorry, there was an error getting your history. Please try again later."
+	}
+	if len(history) == 0 {
+		return "You haven't been sent any texts yet."
+	}
+
+	var b strings.Builder
+	b.WriteString("Your last texts:\n")
+	for _, item := range history {
+		excerpt := "(no longer available)"
+		if item.Content != nil {
+			excerpt = excerptOf(item.Content, user.Language)
+		}
+		fmt.Fprintf(&b, "%d. %s: %s\n", item.Number, formatHistoryDate(item.Date), excerpt)
+	}
+	fmt.Fprintf(&b, "\nSend 'show %d' to get a text again.", history[0].Number)
+	return b.String()
+}
+
+// showReceived resends the nth most recent item the user was sent.
+func (s *Service) showReceived(cleanNumber string, args []string) string {
+	user, err := s.ensureUserExists(cleanNumber)
+	if err != nil {
+		return "Sorry, there was an error getting that text. Please try again later."
+	}
+
+	n, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+	if err != nil || n < 1 || n > maxHistoryItems {
+		return fmt.Sprintf("Please give the number of a text from 'history', between 1 and %d.", maxHistoryItems)
+	}
+
+	release, retryAfter := s.showLimiter.reserve(cleanNumber, time.Now())
+	if release == nil {
+		return fmt.Sprintf("You have already asked for %d past texts today. Please try again in %s.", showLimit, formatWait(retryAfter))
+	}
+	sent := false
+	defer func() {
+		if !sent {
+			release()
+		}
+	}()
+
+	history, err := s.receivedHistory(user, n)
+	if err != nil {
+		log.Printf("Error getting history for %s: %v", cleanNumber, err)
+		return "Sorry, there was an error getting that text. Please try again later."
+	}
+	switch {
+	case len(history) == 0:
+		return "You haven't been sent any texts yet."
+	case n > len(history) && len(history) == 1:
+		return "You have only been sent 1 text. Send 'history' to see it."
+	case n > len(history):
+		return fmt.Sprintf("You have only been sent %d texts. Send 'history' to see them.", len(history))
+	}
+	item := history[n-1]
+	if item.Content == nil {
+		return "Sorry, that text is no longer available."
+	}
+
+	message := s.formatContentMessage(item.Content, user.Language)
+	if _, err := s.SendMessageToUser(user.PhoneNumber, message, textOf(item.Content.ImageURL)); err != nil {
+		log.Printf("Error resending content %s to %s: %v", item.Content.ID, cleanNumber, err)
+		return "Sorry, there was an error sending that text. Please try again later."
+	}
+	sent = true
+
+	// The text itself is the reply.
+	return ""
+}
+
+// excerptOf is the start of the text a user with language reads, cut at a
+// word boundary.
+func excerptOf(c *content.Content, language string) string {
+	text := c.TextEnglish
+	if language == "la" && textOf(c.TextLatin) != "" {
+		text = *c.TextLatin
+	}
+	text = strings.Join(strings.Fields(text), " ")
+
+	runes := []rune(text)
+	if len(runes) <= excerptLength {
+		return text
+	}
+	cut := string(runes[:excerptLength])
+	if space := strings.LastIndex(cut, " "); space > excerptLength/2 {
+		cut = cut[:space]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
+
+func formatHistoryDate(scheduledFor string) string {
+	date, err := time.Parse(deliveries.DateFormat, scheduledFor)
+	if err != nil {
+		return scheduledFor
+	}
+	return date.Format("2 Jan 2006")
+}
@@ -0,0 +1,106 @@
+package twilio
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"novissima/internal/content"
+)
+
+func TestShowReceived(t *testing.T) {
+	tests := []struct {
+		name      string
+		delivered int
+		show      string
+		wantReply string
+		wantSent  bool
+	}{
+		{name: "nothing sent yet", delivered: 0, show: "1", wantReply: "You haven't been sent any texts yet."},
+		{name: "past the only text", delivered: 1, show: "2", wantReply: "You have only been sent 1 text. Send 'history' to see it."},
+		{name: "past the last text", delivered: 2, show: "3", wantReply: "You have only been sent 2 texts. Send 'history' to see them."},
+		{name: "out of range", delivered: 2, show: "0", wantReply: fmt.Sprintf("Please give the number of a text from 'history', between 1 and %d.", maxHistoryItems)},
+		{name: "resends the text", delivered: 2, show: "#2", wantSent: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			user := env.addUser(t, "+15550000001", "en")
+			for i := 0; i < tt.delivered; i++ {
+				c := env.addContent(t, content.ContentCreate{TextEnglish: fmt.Sprintf("Text %d.", i+1)})
+				scheduledFor := time.Date(2026, 11, 1+i, 0, 0, 0, 0, time.UTC)
+				if err := env.deliveries.RecordSent(user.ID, c.ID, scheduledFor, fmt.Sprintf("SM%d", i)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := env.service.showReceived(user.PhoneNumber, []string{tt.show}); got != tt.wantReply {
+				t.Errorf("reply = %q, want %q", got, tt.wantReply)
+			}
+			sent := env.recorder.MessagesTo(user.PhoneNumber)
+			if !tt.wantSent {
+				if len(sent) != 0 {
+					t.Errorf("sent %d messages, want none", len(sent))
+				}
+				return
+			}
+			// Texts are numbered newest first, so #2 is the older one.
+			if len(sent) != 1 || sent[0].Body != "Text 1." {
+				t.Errorf("sent %+v, want Text 1.", sent)
+			}
+		})
+	}
+}
+
+func TestShowReceivedLimit(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.addUser(t, "+15550000001", "en")
+	c := env.addContent(t, content.ContentCreate{TextEnglish: "Remember death."})
+	if err := env.deliveries.RecordSent(user.ID, c.ID, time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC), "SM1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Requests that send nothing do not count towards the limit.
+	for i := 0; i < showLimit+1; i++ {
+		env.service.showReceived(user.PhoneNumber, []string{"2"})
+	}
+	for i := 0; i < showLimit; i++ {
+		if got := env.service.showReceived(user.PhoneNumber, []string{"1"}); got != "" {
+			t.Fatalf("show %d: reply = %q, want the text to be sent", i+1, got)
+		}
+	}
+
+	if got := env.service.showReceived(user.PhoneNumber, []string{"1"}); got == "" {
+		t.Error("show past the limit sent the text again")
+	}
+	if sent := env.recorder.MessagesTo(user.PhoneNumber); len(sent) != showLimit {
+		t.Errorf("sent %d messages, want %d", len(sent), showLimit)
+	}
+}
+
+func TestShowHistoryKeepsDeletedContent(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.addUser(t, "+15550000001", "en")
+	var sent []content.Content
+	for i := 0; i < 2; i++ {
+		c := env.addContent(t, content.ContentCreate{TextEnglish: fmt.Sprintf("Text %d.", i+1)})
+		if err := env.deliveries.RecordSent(user.ID, c.ID, time.Date(2026, 11, 1+i, 0, 0, 0, 0, time.UTC), fmt.Sprintf("SM%d", i)); err != nil {
+			t.Fatal(err)
+		}
+		sent = append(sent, c)
+	}
+	if err := env.content.DeleteContent(sent[1].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	history := env.service.showHistory(user.PhoneNumber, nil)
+	for _, want := range []string{"1. 2 Nov 2026: (no longer available)", "2. 1 Nov 2026: Text 1."} {
+		if !strings.Contains(history, want) {
+			t.Errorf("history = %q, want it to contain %q", history, want)
+		}
+	}
+	if got := env.service.showReceived(user.PhoneNumber, []string{"1"}); got != "Sorry, that text is no longer available." {
+		t.Errorf("show 1 = %q, want the deleted text to be unavailable", got)
+	}
+}
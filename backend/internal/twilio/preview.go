@@ -0,0 +1,122 @@
+package twilio
+
+import (
+	"errors"
+	"log"
+
+	"novissima/internal/content"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNoReviewers = errors.New("no reviewer numbers are configured")
+	ErrNoLatinText = errors.New("content has no Latin text")
+)
+
+// previewLanguages are the language settings a subscriber can choose.
+var previewLanguages = []string{"en", "la", "both"}
+
+// MessagePreview is the message a subscriber with Language would receive.
+// Skipped is set when such subscribers would get nothing, as with Latin
+// readers and content that has no Latin text.
+type MessagePreview struct {
+	Language  string            `json:"language"`
+	Body      string            `json:"body"`
+	MediaURL  string            `json:"media_url,omitempty"`
+	Variables map[string]string `json:"template_variables,omitempty"`
+	Skipped   bool              `json:"skipped,omitempty"`
+	Reason    string            `json:"reason,omitempty"`
+}
+
+// Preview renders content for language, built the same way a broadcast
+// builds it.
+func (s *Service) Preview(c *content.Content, language string) MessagePreview {
+	if language == "la" && textOf(c.TextLatin) == "" {
+		return MessagePreview{
+			Language: language,
+			Skipped:  true,
+			Reason:   "no Latin text, Latin subscribers are not sent this item",
+		}
+	}
+
+	message := s.buildMessage("", s.formatContentMessage(c, language), textOf(c.ImageURL))
+	return MessagePreview{
+		Language:  language,
+		Body:      message.Body,
+		MediaURL:  message.MediaURL,
+		Variables: message.Variables,
+	}
+}
+
+// PreviewAll renders content for every language setting.
+func (s *Service) PreviewAll(c *content.Content) []MessagePreview {
+	previews := make([]MessagePreview, 0, len(previewLanguages))
+	for _, language := range previewLanguages {
+		previews = append(previews, s.Preview(c, language))
+	}
+	return previews
+}
+
+type TestSendResult struct {
+	To         string `json:"to"`
+	MessageSID string `json:"message_sid,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// SendTest sends the content with id, rendered for language, to the
+// configured reviewer numbers and nobody else. Test sends are not recorded
+// as deliveries and do not count as the item having been sent.
+func (s *Service) SendTest(id uuid.UUID, language string) ([]TestSendResult, error) {
+	if len(s.reviewerNumbers) == 0 {
+		return nil, ErrNoReviewers
+	}
+	c, err := s.contentService.GetContent(id)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := s.Preview(&c, language)
+	if preview.Skipped {
+		return nil, ErrNoLatinText
+	}
+
+	var sent, failed []string
+	results := make([]TestSendResult, 0, len(s.reviewerNumbers))
+	for _, number := range s.reviewerNumbers {
+		result := TestSendResult{To: number}
+		messageSID, err := s.SendMessageToUser(number, preview.Body, preview.MediaURL)
+		if err != nil {
+			log.Printf("Error sending test of %s to %s: %v", id, number, err)
+			result.Error = err.Error()
+			failed = append(failed, number)
+		} else {
+			result.MessageSID = messageSID
+			sent = append(sent, number)
+		}
+		results = append(results, result)
+	}
+
+	if err := s.loggingService.LogTestSent(id, language, sent, failed); err != nil {
+		log.Printf("Error logging test send of %s: %v", id, err)
+	}
+	return results, nil
+}
+
+// NotifyReviewers sends message to the configured reviewer numbers and
+// returns how many of them it reached.
+func (s *Service) NotifyReviewers(message string) (int, error) {
+	if len(s.reviewerNumbers) == 0 {
+		return 0, ErrNoReviewers
+	}
+
+	reached := 0
+	for _, number := range s.reviewerNumbers {
+		if _, err := s.SendMessageToUser(number, message, ""); err != nil {
+			log.Printf("Error notifying reviewer %s: %v", number, err)
+			continue
+		}
+		reached++
+	}
+	return reached, nil
+}
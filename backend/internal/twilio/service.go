@@ -1,124 +1,199 @@
 package twilio
 
 import (
-	"crypto/hmac"
-	"crypto/sha1"
-	"encoding/base64"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"novissima/internal/content"
+	"novissima/internal/deliveries"
+	"novissima/internal/latin"
+	"novissima/internal/logging"
+	"novissima/internal/messaging"
 	"novissima/internal/users"
-	"sort"
+	"strconv"
 	"strings"
-
-	twilioApi "github.com/twilio/twilio-go/rest/api/v2010"
+	"time"
 )
 			
+// templateBucketPrefix marks the URLs of images in the Supabase bucket the
+// content template serves media from.
+const templateBucketPrefix = "novissima-images/"
+
 type Service struct {
-	client      *Client
+	messenger   messaging.Messenger
 	userService *users.Service
 	contentService *content.Service
-	accountSid  string
+	deliveryService *deliveries.Service
+	loggingService *logging.Service
 	authToken   string
-	phoneNumber string
-	contentSid string
-	messagingServiceSid string
+	publicBaseURL string
+	validateSignatures bool
+	reviewerNumbers []string
+	commands *commandRegistry
+	todayLimiter *rateLimiter
+	showLimiter *rateLimiter
+	lexicon *latin.Lexicon
 }
 
-func NewService(userService *users.Service, contentService *content.Service, accountSid, authToken, phoneNumber, contentSid, messagingServiceSid string) *Service {
-	return &Service{
-		client:      NewClient(accountSid, authToken, phoneNumber),
+func NewService(userService *users.Service, contentService *content.Service, deliveryService *deliveries.Service, loggingService *logging.Service, messenger messaging.Messenger, authToken, publicBaseURL string, validateSignatures bool, reviewerNumbers []string, lexicon *latin.Lexicon) *Service {
+	s := &Service{
+		messenger:   messenger,
 		userService: userService,	
 		contentService: contentService,
-		accountSid:  accountSid,
+		deliveryService: deliveryService,
+		loggingService: loggingService,
 		authToken:   authToken,
-		phoneNumber: phoneNumber,
-		contentSid: contentSid,
-		messagingServiceSid: messagingServiceSid,
+		publicBaseURL: publicBaseURL,
+		validateSignatures: validateSignatures,
+		reviewerNumbers: reviewerNumbers,
+		todayLimiter: newRateLimiter(todayLimit, todayWindow),
+		showLimiter: newRateLimiter(showLimit, showWindow),
+		lexicon: lexicon,
 	}
+	s.commands = s.registerCommands()
+	return s
 }
 
-func (s *Service) SendMessageToUser(phoneNumber, message string, mediaUrl string) error {
-	params := &twilioApi.CreateMessageParams{}
-	params.SetTo("whatsapp:" + phoneNumber)
-	params.SetFrom("whatsapp:" + s.client.phoneNumber)
-	fmt.Printf("contentSid: %s\n", s.contentSid)
-	params.SetContentSid(s.contentSid)
-	params.SetMessagingServiceSid(s.messagingServiceSid)
-	
-	formattedMediaUrl := strings.Split(mediaUrl, "novissima-images/")[1]
-	
-	contentVariables := map[string]string{
-		"1": message,
-		"2": formattedMediaUrl,
+func (s *Service) SendMessageToUser(phoneNumber, message string, mediaUrl string) (string, error) {
+	return s.messenger.Send(s.buildMessage(phoneNumber, message, mediaUrl))
+}
+
+func (s *Service) buildMessage(phoneNumber, message string, mediaUrl string) messaging.Message {
+	msg := messaging.Message{
+		To:             phoneNumber,
+		Body:           message,
+		MediaURL:       mediaUrl,
+		StatusCallback: s.statusCallbackURL(),
 	}
-	
-	variablesJSON, _ := json.Marshal(contentVariables)
-	params.SetContentVariables(string(variablesJSON))
 
-	twilioMessage, err := s.client.twilioClient.Api.CreateMessage(params)
-	if err != nil {
-		return err
+	// The content template prefixes {{2}} with the Supabase bucket URL, so it
+	// only fits images stored there. Anything else, including text without an
+	// image, is sent as a free-form message with the full media URL.
+	if _, objectPath, found := strings.Cut(mediaUrl, templateBucketPrefix); found {
+		msg.Variables = map[string]string{
+			"1": message,
+			"2": objectPath,
+		}
 	}
-	jsonMsg, _ := json.MarshalIndent(twilioMessage, "", "  ")
-	log.Printf("Twilio message: %s", jsonMsg)
+	return msg
+}
 
-	return err
+// statusCallbackURL is where Twilio reports delivery status. It needs the
+// public base URL, as there is no incoming request to derive it from.
+func (s *Service) statusCallbackURL() string {
+	if s.publicBaseURL == "" {
+		return ""
+	}
+	return strings.TrimSuffix(s.publicBaseURL, "/") + "/twilio/status"
 }
 
+// formatContentMessage renders content for a user with language. Empty
+// optional fields are treated as missing, so they never leave a stray
+// separator or empty source line.
 func (s *Service) formatContentMessage(content *content.Content, language string) string {
 	var formattedContent string
+	latin := textOf(content.TextLatin)
 			
 	switch language {
-	case "en":
-		formattedContent = content.TextEnglish
 	case "la":
-		formattedContent = *content.TextLatin
+		// Latin users are skipped when there is no Latin text; fall back to
+		// English rather than send an empty message.
+		if latin == "" {
+			formattedContent = content.TextEnglish
+		} else {
+			formattedContent = latin
+		}
 	case "both":
-		if content.TextLatin == nil {
+		if latin == "" {
 			formattedContent = content.TextEnglish
 		} else {
-			formattedContent = *content.TextLatin + "\n\n" + "---" + "\n\n" + content.TextEnglish
+			formattedContent = latin + "\n\n" + "---" + "\n\n" + content.TextEnglish
 		}
 	default:
 		formattedContent = content.TextEnglish
 	}
 
-	if content.ImageSource != nil {
-		formattedContent += "\n\n" + "---" + "\n\n" + "*" + *content.ImageSource + "*"
+	if imageSource := textOf(content.ImageSource); imageSource != "" {
+		formattedContent += "\n\n" + "---" + "\n\n" + "*" + imageSource + "*"
 	}
-	if content.TextSource != nil {
-		formattedContent += "\n\n" + "*" + *content.TextSource + "*"	
+	if textSource := textOf(content.TextSource); textSource != "" {
+		formattedContent += "\n\n" + "*" + textSource + "*"	
 	}
 
 	return formattedContent
 }
 
-func (s *Service) SendMessageToAllUsers(content *content.Content) error {
+// textOf returns the value of an optional field, or "" if it is unset or
+// blank.
+func textOf(value *string) string {
+	if value == nil || strings.TrimSpace(*value) == "" {
+		return ""
+	}
+	return *value
+}
+
+// SendMessageToAllUsers broadcasts content to every active user as the
+// delivery for scheduledFor.
+func (s *Service) SendMessageToAllUsers(content *content.Content, scheduledFor time.Time) error {
 	users, err := s.userService.GetAllActiveUsers()
 	if err != nil {
 		return err
 	}
 
+	return s.SendMessageToUsers(content, scheduledFor, users)
+}
+
+// SendMessageToUsers sends content to recipients as the delivery for
+// scheduledFor. Each user's slot is claimed with a pending delivery before the
+// message is sent, and users whose slot already has a delivery are skipped, so
+// a send that was interrupted can be run again without sending anyone the
+// message twice.
+func (s *Service) SendMessageToUsers(content *content.Content, scheduledFor time.Time, recipients []users.User) error {
 	failedToSend := []string{}
-	for _, user := range users {
+	sent := 0
+	skipped := 0
+	for _, user := range recipients {
+
+		if user.Language == "la" && textOf(content.TextLatin) == "" {
+			continue
+		}
 
-		if user.Language == "la" && content.TextLatin == nil {
+		if err := s.deliveryService.Claim(user.ID, content.ID, scheduledFor); err != nil {
+			if !errors.Is(err, deliveries.ErrDeliveryExists) {
+				log.Printf("Error claiming delivery for user %s, not sending: %v", user.PhoneNumber, err)
+			}
+			skipped++
 			continue
 		}
 
 		messageToSend := s.formatContentMessage(content, user.Language)
-		
-		if err := s.SendMessageToUser(user.PhoneNumber, messageToSend, *content.ImageURL); err != nil {
+
+		imageURL := textOf(content.ImageURL)
+
+		messageSID, err := s.SendMessageToUser(user.PhoneNumber, messageToSend, imageURL)
+		if err != nil {
 			log.Printf("Error sending message to user %s: %v", user.PhoneNumber, err)
 			failedToSend = append(failedToSend, user.PhoneNumber)
+			if err := s.deliveryService.RecordFailed(user.ID, content.ID, scheduledFor, err); err != nil {
+				log.Printf("Error recording failed delivery for user %s: %v", user.PhoneNumber, err)
+			}
 			continue
 		}
+		sent++
+
+		if err := s.deliveryService.RecordSent(user.ID, content.ID, scheduledFor, messageSID); err != nil {
+			log.Printf("Error recording delivery for user %s: %v", user.PhoneNumber, err)
+		}
+	}
+
+	if sent == 0 && len(failedToSend) == 0 {
+		return nil
 	}
 
-	err = s.contentService.UpdateLastSent(content.ID)
+	log.Printf("Sent content %s for %s: %d sent, %d failed, %d already served", content.ID, deliveries.DateKey(scheduledFor), sent, len(failedToSend), skipped)
+
+	err := s.contentService.UpdateLastSent(content.ID, scheduledFor)
 	if err != nil {
 		log.Printf("Error updating last sent for content %s: %v", content.ID, err)
 	}
@@ -130,69 +205,28 @@ func (s *Service) SendMessageToAllUsers(content *content.Content) error {
 	return nil
 }
 
-func (s *Service) validateRequest(r *http.Request) bool {
-	
-	signature := r.Header.Get("X-Twilio-Signature")
-	if signature == "" {
-		return false
-	}
-
-	
-	fullURL := "https://" + r.Host + r.URL.String()
-	
-	
-	values := r.URL.Query()
-	for key, val := range r.PostForm {
-		values[key] = val
-	}
+// processMessage runs the command in body and returns the reply.
+func (s *Service) processMessage(from, body string) string {
+	cleanNumber := strings.TrimPrefix(from, "whatsapp:")
 
-	
-	var keys []string
-	for k := range values {
-		keys = append(keys, k)
+	parts := strings.Fields(body)
+	if len(parts) == 0 {
+		return "Hello! Text 'help' to see available commands."
 	}
-	sort.Strings(keys)
 
-	
-	var buf strings.Builder
-	buf.WriteString(fullURL)
-	for _, key := range keys {
-		buf.WriteString(key)
-		for _, val := range values[key] {
-			buf.WriteString(val)
+	name, args := parts[0], parts[1:]
+	command, ok := s.commands.lookup(name)
+	if !ok {
+		if suggestion, ok := s.commands.suggest(name); ok {
+			return fmt.Sprintf("Unknown command '%s', did you mean '%s'? Text 'help' to see available commands.", name, suggestion.name)
 		}
+		return "Unknown command. Text 'help' to see available commands."
 	}
 
-	
-	h := hmac.New(sha1.New, []byte(s.authToken))
-	h.Write([]byte(buf.String()))
-	expectedSignature := base64.StdEncoding.EncodeToString(h.Sum(nil))
-
-	return signature == expectedSignature
-}
-
-func (s *Service) processMessage(from, body string) string {
-	
-	cleanNumber := strings.TrimPrefix(from, "whatsapp:")
-	
-	parts := strings.Fields(strings.TrimSpace(body))
-	
-	command := strings.ToLower(parts[0])
-
-	switch command {
-	case "start":
-		return s.startSubscription(cleanNumber)
-	case "stop":
-		return s.stopSubscription(cleanNumber)
-	case "help":
-		return s.getHelp()
-	case "lang":
-		return s.setLanguage(cleanNumber, parts)
-	case "status":
-		return s.getStatus(cleanNumber)
-	default:
-		return "Unknown command. Text 'help' to see available commands."
+	if len(args) < command.minArgs || (command.args != "" && len(args) > command.maxArgs) {
+		return "Usage: " + command.usage()
 	}
+	return command.handler(cleanNumber, args)
 }
 
 func (s *Service) ensureUserExists(cleanNumber string) (*users.User, error) {
@@ -258,15 +292,13 @@ func (s *Service) stopSubscription(cleanNumber string) string {
 	return "Your daily content subscription has been stopped. Send 'start' to resume."
 }
 
-func (s *Service) getHelp() string {
-	return `Available commands:
-• start - Start receiving daily content (registers you if needed)
-• stop - Stop receiving daily content
-• help - Show this help message
-• lang [en|la|both] - Set language (English/Latin/Both)
-• status - Get the status of your subscription
-
-Example: "start" or "lang en" or "status"`
+// getHelp lists the commands, in Latin for users who read Latin only.
+func (s *Service) getHelp(cleanNumber string) string {
+	language := "en"
+	if user, err := s.userService.GetUserByPhoneNumber(cleanNumber); err == nil {
+		language = user.Language
+	}
+	return s.commands.helpText(language)
 }
 
 func (s *Service) getStatus(cleanNumber string) string {
@@ -284,7 +316,7 @@ func (s *Service) getStatus(cleanNumber string) string {
 	return fmt.Sprintf("Subs is: %s and your language is set to: %s", userStatus, selectedLanguage)
 }
 
-func (s *Service) setLanguage(cleanNumber string, parts []string) string {
+func (s *Service) setLanguage(cleanNumber string, args []string) string {
 	
 	user, err := s.ensureUserExists(cleanNumber)
 	if err != nil {
@@ -292,11 +324,11 @@ func (s *Service) setLanguage(cleanNumber string, parts []string) string {
 	}
 	
 	
-	if len(parts) < 2 {
+	if len(args) < 1 {
 		return "Please specify a language. Usage: lang [en|la|both]"
 	}
 
-	language := strings.ToLower(parts[1])
+	language := strings.ToLower(args[0])
 	switch language {
 	case "en":
 		if user.Language == "en" {
@@ -330,11 +362,117 @@ func (s *Service) setLanguage(cleanNumber string, parts []string) string {
 	}
 }
 
+func (s *Service) setDeliveryTime(cleanNumber string, args []string) string {
+	user, err := s.ensureUserExists(cleanNumber)
+	if err != nil {
+		return "Sorry, there was an error updating your delivery time. Please try again later."
+	}
+
+	if len(args) == 0 {
+		return fmt.Sprintf("Your daily text is sent at %02d:00 (%s). Usage: time [hour] [timezone], e.g. \"time 7 America/Chicago\"", user.PreferredHour, user.Timezone)
+	}
+
+	timezone := user.Timezone
+	hour := user.PreferredHour
+	for _, arg := range args {
+		if parsedHour, ok := parseHour(arg); ok {
+			hour = parsedHour
+			continue
+		}
+		if name, ok := normalizeTimezone(arg); ok {
+			timezone = name
+			continue
+		}
+		return fmt.Sprintf("Sorry, I didn't understand \"%s\". Use an hour like 7 or 19:00 and a timezone like Asia/Manila.", arg)
+	}
+
+	err = s.userService.UpdateUserDeliveryTime(cleanNumber, timezone, hour)
+	if err != nil {
+		return "Sorry, there was an error updating your delivery time. Please try again later."
+	}
+
+	return fmt.Sprintf("Your daily text will be sent at %02d:00 (%s).", hour, timezone)
+}
+
+// parseHour accepts "7", "07", "19:00", "7am" and "7pm".
+func parseHour(arg string) (int, bool) {
+	value := strings.ToLower(arg)
+	meridiem := ""
+	if strings.HasSuffix(value, "am") || strings.HasSuffix(value, "pm") {
+		meridiem = value[len(value)-2:]
+		value = value[:len(value)-2]
+	}
+	value = strings.TrimSuffix(value, ":00")
+
+	hour, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, false
+	}
+
+	switch meridiem {
+	case "am":
+		if hour < 1 || hour > 12 {
+			return 0, false
+		}
+		hour %= 12
+	case "pm":
+		if hour < 1 || hour > 12 {
+			return 0, false
+		}
+		hour = hour%12 + 12
+	}
+
+	if hour < 0 || hour > 23 {
+		return 0, false
+	}
+	return hour, true
+}
+
+// normalizeTimezone resolves an IANA zone name typed in any case, e.g.
+// "america/new_york" to "America/New_York".
+func normalizeTimezone(arg string) (string, bool) {
+	candidates := []string{arg, strings.ToUpper(arg), titleCaseZone(arg)}
+	for _, candidate := range candidates {
+		if candidate == "" || candidate == "Local" {
+			continue
+		}
+		if _, err := time.LoadLocation(candidate); err == nil {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+func titleCaseZone(name string) string {
+	var b strings.Builder
+	upperNext := true
+	for _, r := range strings.ToLower(name) {
+		if upperNext {
+			b.WriteString(strings.ToUpper(string(r)))
+		} else {
+			b.WriteRune(r)
+		}
+		upperNext = r == '/' || r == '_' || r == '-'
+	}
+	return b.String()
+}
+
+// sendResponse replies with message, or with no message when it is empty.
 func (s *Service) sendResponse(w http.ResponseWriter, message string) {
+	if message == "" {
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<Response></Response>`))
+		return
+	}
+
+	// Replies can quote what the user sent.
+	escaped := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(message)
+
 	twimlResponse := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
 <Response>
     <Message>%s</Message>
-</Response>`, message)
+</Response>`, escaped)
 
 	w.Header().Set("Content-Type", "application/xml")
 	w.Write([]byte(twimlResponse))
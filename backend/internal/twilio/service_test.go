@@ -0,0 +1,264 @@
+package twilio
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"novissima/internal/content"
+	"novissima/internal/database"
+	"novissima/internal/deliveries"
+	"novissima/internal/latin"
+	"novissima/internal/logging"
+	"novissima/internal/messaging"
+	"novissima/internal/storage"
+	"novissima/internal/users"
+)
+
+const reviewerNumber = "+15550000100"
+
+type testEnv struct {
+	service    *Service
+	recorder   *messaging.Recorder
+	users      *users.Service
+	content    content.ContentStore
+	deliveries *deliveries.Service
+}
+
+// newTestEnv wires a Service to the in-memory store and a Recorder.
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	db := database.NewMemoryDB()
+	if err := db.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	stores := db.Stores()
+	loggingService := logging.NewService(stores.Logs)
+	userService := users.NewService(stores.Users, loggingService)
+	contentService := content.NewService(
+		stores.Content,
+		stores.Overrides,
+		storage.NewLocalStore(t.TempDir(), "http://localhost/images"),
+		loggingService,
+		content.RotationPolicy{},
+	)
+	deliveryService := deliveries.NewService(stores.Deliveries)
+	lexicon, err := latin.LoadLexicon()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := messaging.NewRecorder()
+
+	return &testEnv{
+		service:    NewService(userService, contentService, deliveryService, loggingService, recorder, "", "", false, []string{reviewerNumber}, lexicon),
+		recorder:   recorder,
+		users:      userService,
+		content:    stores.Content,
+		deliveries: deliveryService,
+	}
+}
+
+func (e *testEnv) addContent(t *testing.T, create content.ContentCreate) content.Content {
+	t.Helper()
+
+	if create.Theme == "" {
+		create.Theme = "death"
+	}
+	c, err := e.content.InsertContent(create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func (e *testEnv) addUser(t *testing.T, phoneNumber, language string) users.User {
+	t.Helper()
+
+	if _, err := e.users.AddUser(phoneNumber); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.users.UpdateUserLanguage(phoneNumber, language); err != nil {
+		t.Fatal(err)
+	}
+	user, err := e.users.GetUserByPhoneNumber(phoneNumber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func TestSendMessageToUsersMedia(t *testing.T) {
+	tests := []struct {
+		name          string
+		imageURL      *string
+		wantVariables map[string]string
+	}{
+		{
+			name:     "supabase bucket image uses the template",
+			imageURL: ptr("https://project.supabase.co/storage/v1/object/public/novissima-images/death/skull.jpg"),
+			wantVariables: map[string]string{
+				"1": "Memento mori.",
+				"2": "death/skull.jpg",
+			},
+		},
+		{
+			name:     "local image is sent free-form",
+			imageURL: ptr("http://localhost/images/death/skull.jpg"),
+		},
+		{
+			name:     "s3 image is sent free-form",
+			imageURL: ptr("https://novissima.s3.eu-west-1.amazonaws.com/death/skull.jpg"),
+		},
+		{
+			name: "text only is sent free-form",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			c := env.addContent(t, content.ContentCreate{TextEnglish: "Memento mori.", ImageURL: tt.imageURL})
+			user := env.addUser(t, "+15550000001", "en")
+			scheduledFor := time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC)
+
+			if err := env.service.SendMessageToUsers(&c, scheduledFor, []users.User{user}); err != nil {
+				t.Fatal(err)
+			}
+
+			sent := env.recorder.MessagesTo(user.PhoneNumber)
+			if len(sent) != 1 {
+				t.Fatalf("sent %d messages, want 1", len(sent))
+			}
+			msg := sent[0]
+			if msg.Body != "Memento mori." {
+				t.Errorf("Body = %q, want %q", msg.Body, "Memento mori.")
+			}
+			wantMedia := ""
+			if tt.imageURL != nil {
+				wantMedia = *tt.imageURL
+			}
+			if msg.MediaURL != wantMedia {
+				t.Errorf("MediaURL = %q, want %q", msg.MediaURL, wantMedia)
+			}
+			if len(msg.Variables) != len(tt.wantVariables) {
+				t.Fatalf("Variables = %v, want %v", msg.Variables, tt.wantVariables)
+			}
+			for key, want := range tt.wantVariables {
+				if msg.Variables[key] != want {
+					t.Errorf("Variables[%q] = %q, want %q", key, msg.Variables[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestSendMessageToUsersRecipients(t *testing.T) {
+	env := newTestEnv(t)
+	english := env.addContent(t, content.ContentCreate{TextEnglish: "Remember death."})
+	bilingual := env.addContent(t, content.ContentCreate{TextEnglish: "Remember death.", TextLatin: ptr("Memento mori.")})
+	scheduledFor := time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC)
+
+	en := env.addUser(t, "+15550000001", "en")
+	la := env.addUser(t, "+15550000002", "la")
+	both := env.addUser(t, "+15550000003", "both")
+	failing := env.addUser(t, "+15550000004", "en")
+	env.recorder.FailFor(failing.PhoneNumber, errors.New("unreachable"))
+	recipients := []users.User{en, la, both, failing}
+
+	tests := []struct {
+		name    string
+		content content.Content
+		want    map[string][]string
+	}{
+		{
+			name:    "latin readers are skipped without latin text",
+			content: english,
+			want: map[string][]string{
+				en.PhoneNumber:   {"Remember death."},
+				both.PhoneNumber: {"Remember death."},
+			},
+		},
+		{
+			name:    "each language gets its own text",
+			content: bilingual,
+			want: map[string][]string{
+				en.PhoneNumber:   {"Remember death."},
+				la.PhoneNumber:   {"Memento mori."},
+				both.PhoneNumber: {"Memento mori.", "Remember death."},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env.recorder.Reset()
+			env.recorder.FailFor(failing.PhoneNumber, errors.New("unreachable"))
+
+			// A second run must not send anything again.
+			for run := 0; run < 2; run++ {
+				if err := env.service.SendMessageToUsers(&tt.content, scheduledFor, recipients); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for _, user := range recipients {
+				sent := env.recorder.MessagesTo(user.PhoneNumber)
+				want := tt.want[user.PhoneNumber]
+				if want == nil {
+					if len(sent) != 0 {
+						t.Errorf("%s got %d messages, want none", user.PhoneNumber, len(sent))
+					}
+					continue
+				}
+				if len(sent) != 1 {
+					t.Fatalf("%s got %d messages, want 1", user.PhoneNumber, len(sent))
+				}
+				for _, text := range want {
+					if !strings.Contains(sent[0].Body, text) {
+						t.Errorf("%s got %q, want it to contain %q", user.PhoneNumber, sent[0].Body, text)
+					}
+				}
+			}
+
+			summaries, err := env.deliveries.Summaries(&tt.content.ID, deliveries.DateKey(scheduledFor))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(summaries) != 1 {
+				t.Fatalf("%d summaries, want 1", len(summaries))
+			}
+			if queued := summaries[0].ByStatus[deliveries.StatusQueued]; queued != len(tt.want) {
+				t.Errorf("%d deliveries queued, want %d", queued, len(tt.want))
+			}
+			if failed := summaries[0].ByStatus[deliveries.StatusFailed]; failed != 1 {
+				t.Errorf("%d deliveries failed, want 1", failed)
+			}
+		})
+	}
+}
+
+func TestSendMessageToUsersSkipsClaimedSlots(t *testing.T) {
+	env := newTestEnv(t)
+	c := env.addContent(t, content.ContentCreate{TextEnglish: "Remember death."})
+	user := env.addUser(t, "+15550000001", "en")
+	scheduledFor := time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC)
+
+	// A pending delivery is what a run that stopped between sending and
+	// recording the send leaves behind.
+	if err := env.deliveries.Claim(user.ID, c.ID, scheduledFor); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := env.service.SendMessageToUsers(&c, scheduledFor, []users.User{user}); err != nil {
+		t.Fatal(err)
+	}
+	if sent := env.recorder.MessagesTo(user.PhoneNumber); len(sent) != 0 {
+		t.Errorf("sent %d messages to a claimed slot, want none", len(sent))
+	}
+}
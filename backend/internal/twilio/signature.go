@@ -0,0 +1,83 @@
+package twilio
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	twilioClient "github.com/twilio/twilio-go/client"
+)
+
+// RequireSignature rejects requests that do not carry a valid
+// X-Twilio-Signature. Twilio signs the public URL it called, so behind Fly's
+// proxy the URL is rebuilt from publicBaseURL or, failing that, from the
+// X-Forwarded-Proto and X-Forwarded-Host headers.
+func (s *Service) RequireSignature(next http.HandlerFunc) http.HandlerFunc {
+	if !s.validateSignatures {
+		log.Println("Warning: Twilio signature validation is disabled")
+		return next
+	}
+
+	validator := twilioClient.NewRequestValidator(s.authToken)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+
+		signature := r.Header.Get("X-Twilio-Signature")
+		if signature == "" {
+			s.rejectRequest(w, r, "missing signature")
+			return
+		}
+
+		params := make(map[string]string, len(r.PostForm))
+		for key := range r.PostForm {
+			params[key] = r.PostForm.Get(key)
+		}
+
+		if !validator.Validate(s.requestURL(r), params, signature) {
+			s.rejectRequest(w, r, "invalid signature")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func (s *Service) requestURL(r *http.Request) string {
+	if s.publicBaseURL != "" {
+		return strings.TrimSuffix(s.publicBaseURL, "/") + r.URL.RequestURI()
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := firstHeaderValue(r, "X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+
+	host := r.Host
+	if forwardedHost := firstHeaderValue(r, "X-Forwarded-Host"); forwardedHost != "" {
+		host = forwardedHost
+	}
+
+	return scheme + "://" + host + r.URL.RequestURI()
+}
+
+func (s *Service) rejectRequest(w http.ResponseWriter, r *http.Request, reason string) {
+	log.Printf("Rejected Twilio request to %s from %s: %s", r.URL.Path, r.RemoteAddr, reason)
+	if err := s.loggingService.LogWebhookRejected(r.URL.Path, reason, r.RemoteAddr); err != nil {
+		log.Printf("Error logging rejected webhook: %v", err)
+	}
+	http.Error(w, "Forbidden", http.StatusForbidden)
+}
+
+// firstHeaderValue returns the first entry of a possibly comma-separated
+// header, as proxies append their own values to X-Forwarded-*.
+func firstHeaderValue(r *http.Request, name string) string {
+	value, _, _ := strings.Cut(r.Header.Get(name), ",")
+	return strings.TrimSpace(value)
+}
@@ -0,0 +1,126 @@
+package twilio
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"novissima/internal/users"
+)
+
+// listThemes shows the themes that can be followed, marking the ones the
+// user follows.
+func (s *Service) listThemes(cleanNumber string) string {
+	user, err := s.ensureUserExists(cleanNumber)
+	if err != nil {
+		return "Sorry, there was an error getting the themes. Please try again later."
+	}
+
+	themes, err := s.contentService.Themes()
+	if err != nil {
+		log.Printf("Error listing themes: %v", err)
+		return "Sorry, there was an error getting the themes. Please try again later."
+	}
+	if len(themes) == 0 {
+		return "There are no themes to follow yet."
+	}
+
+	followed, err := s.userService.FollowedThemes(user.ID)
+	if err != nil {
+		log.Printf("Error getting followed themes for %s: %v", cleanNumber, err)
+		return "Sorry, there was an error getting the themes. Please try again later."
+	}
+
+	var b strings.Builder
+	b.WriteString("Themes:\n")
+	for _, theme := range themes {
+		b.WriteString("• " + theme)
+		if slices.Contains(followed, theme) {
+			b.WriteString(" (following)")
+		}
+		b.WriteString("\n")
+	}
+	if len(followed) == 0 {
+		fmt.Fprintf(&b, "\nYou follow no themes, so you get the daily text everyone gets. Send e.g. 'follow %s' to get only the themes you choose.", themes[0])
+	} else {
+		fmt.Fprintf(&b, "\nSend e.g. 'unfollow %s' to stop following a theme.", followed[0])
+	}
+	return b.String()
+}
+
+func (s *Service) followTheme(cleanNumber string, args []string) string {
+	user, err := s.ensureUserExists(cleanNumber)
+	if err != nil {
+		return "Sorry, there was an error following the theme. Please try again later."
+	}
+
+	themes, err := s.contentService.Themes()
+	if err != nil {
+		log.Printf("Error listing themes: %v", err)
+		return "Sorry, there was an error following the theme. Please try again later."
+	}
+	theme, ok := findTheme(themes, args[0])
+	if !ok {
+		return fmt.Sprintf("There is no theme called '%s'. Send 'themes' to see the list.", args[0])
+	}
+
+	followed, err := s.userService.FollowedThemes(user.ID)
+	if err != nil {
+		log.Printf("Error getting followed themes for %s: %v", cleanNumber, err)
+		return "Sorry, there was an error following the theme. Please try again later."
+	}
+	if slices.Contains(followed, theme) {
+		return fmt.Sprintf("You already follow %s.", theme)
+	}
+
+	if err := s.userService.FollowTheme(user.ID, theme); err != nil {
+		log.Printf("Error following theme %s for %s: %v", theme, cleanNumber, err)
+		return "Sorry, there was an error following the theme. Please try again later."
+	}
+
+	followed = append(followed, theme)
+	slices.Sort(followed)
+	return fmt.Sprintf("You now follow %s. Your daily text will come from: %s.", theme, strings.Join(followed, ", "))
+}
+
+func (s *Service) unfollowTheme(cleanNumber string, args []string) string {
+	user, err := s.ensureUserExists(cleanNumber)
+	if err != nil {
+		return "Sorry, there was an error unfollowing the theme. Please try again later."
+	}
+
+	followed, err := s.userService.FollowedThemes(user.ID)
+	if err != nil {
+		log.Printf("Error getting followed themes for %s: %v", cleanNumber, err)
+		return "Sorry, there was an error unfollowing the theme. Please try again later."
+	}
+	// Themes whose content was retired can still be unfollowed, so match
+	// against what the user follows rather than the current themes.
+	theme, ok := findTheme(followed, args[0])
+	if !ok {
+		return fmt.Sprintf("You don't follow '%s'. Send 'themes' to see what you follow.", args[0])
+	}
+
+	err = s.userService.UnfollowTheme(user.ID, theme)
+	if err != nil && !errors.Is(err, users.ErrNotFollowing) {
+		log.Printf("Error unfollowing theme %s for %s: %v", theme, cleanNumber, err)
+		return "Sorry, there was an error unfollowing the theme. Please try again later."
+	}
+
+	if len(followed) == 1 {
+		return fmt.Sprintf("You no longer follow %s. You follow no themes now, so you will get the daily text everyone gets.", theme)
+	}
+	return fmt.Sprintf("You no longer follow %s.", theme)
+}
+
+// findTheme matches name against themes ignoring case.
+func findTheme(themes []string, name string) (string, bool) {
+	for _, theme := range themes {
+		if strings.EqualFold(theme, name) {
+			return theme, true
+		}
+	}
+	return "", false
+}
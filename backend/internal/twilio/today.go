@@ -0,0 +1,173 @@
+package twilio
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"novissima/internal/content"
+)
+
+// Subscribers can ask for the day's text again this many times per window.
+const (
+	todayLimit  = 3
+	todayWindow = 24 * time.Hour
+)
+
+// rateLimiter allows each key limit events per sliding window. It is kept
+// in memory, so limits reset when the process restarts.
+type rateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	events map[string][]time.Time
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, window: window, events: map[string][]time.Time{}}
+}
+
+// reserve counts an event for key at now if key is under its limit, checking
+// and recording under one lock so concurrent requests cannot both take the
+// last slot. It returns a release func that gives the slot back, for when
+// the event does not happen after all. If key is at its limit, reserve
+// returns how long it has to wait instead.
+func (l *rateLimiter) reserve(key string, now time.Time) (release func(), retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	recent := l.prune(key, now)
+	if len(recent) >= l.limit {
+		return nil, recent[0].Add(l.window).Sub(now)
+	}
+	l.events[key] = append(recent, now)
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		events := l.events[key]
+		for i, at := range events {
+			if at.Equal(now) {
+				l.events[key] = append(events[:i:i], events[i+1:]...)
+				break
+			}
+		}
+		if len(l.events[key]) == 0 {
+			delete(l.events, key)
+		}
+	}, 0
+}
+
+// prune drops the events of key that have left the window. Callers must
+// hold l.mu.
+func (l *rateLimiter) prune(key string, now time.Time) []time.Time {
+	recent := l.events[key][:0]
+	for _, at := range l.events[key] {
+		if now.Sub(at) < l.window {
+			recent = append(recent, at)
+		}
+	}
+	if len(recent) == 0 {
+		delete(l.events, key)
+		return nil
+	}
+	l.events[key] = recent
+	return recent
+}
+
+// sendToday sends the user the item the broadcast sent them for their
+// current local date, or the one it will send if it has not run for them
+// yet. It is not recorded as a delivery, so a user who asks before their
+// delivery hour still gets the broadcast.
+func (s *Service) sendToday(cleanNumber string) string {
+	user, err := s.ensureUserExists(cleanNumber)
+	if err != nil {
+		return "Sorry, there was an error getting today's text. Please try again later."
+	}
+
+	// Only texts actually sent count towards the limit, so the slot is given
+	// back on any failure.
+	now := time.Now()
+	release, retryAfter := s.todayLimiter.reserve(cleanNumber, now)
+	if release == nil {
+		return fmt.Sprintf("You have already asked for today's text %d times. Please try again in %s.", todayLimit, formatWait(retryAfter))
+	}
+	sent := false
+	defer func() {
+		if !sent {
+			release()
+		}
+	}()
+
+	date, _ := user.DueDate(now)
+	dailyContent, err := s.deliveredContent(user.ID, date)
+	if err != nil {
+		log.Printf("Error getting today's delivery for %s: %v", cleanNumber, err)
+		return "Sorry, there was an error getting today's text. Please try again later."
+	}
+	if dailyContent == nil {
+		themes, err := s.userService.FollowedThemes(user.ID)
+		if err != nil {
+			log.Printf("Error getting followed themes for %s: %v", cleanNumber, err)
+			return "Sorry, there was an error getting today's text. Please try again later."
+		}
+		dailyContent, err = s.contentService.GetDailyContentFor(date, user.Language, themes)
+		if err != nil {
+			log.Printf("Error getting today's content for %s: %v", cleanNumber, err)
+			return "Sorry, there is no text for today yet."
+		}
+	}
+
+	message := s.formatContentMessage(dailyContent, user.Language)
+	if _, err := s.SendMessageToUser(user.PhoneNumber, message, textOf(dailyContent.ImageURL)); err != nil {
+		log.Printf("Error sending today's content to %s: %v", cleanNumber, err)
+		return "Sorry, there was an error sending today's text. Please try again later."
+	}
+	sent = true
+
+	// The text itself is the reply.
+	return ""
+}
+
+// deliveredContent returns the item the delivery ledger says the user was
+// sent for date, or nil if there is none. Selecting again could pick a
+// different item once an override or approval has been added since the
+// broadcast.
+func (s *Service) deliveredContent(userID uuid.UUID, date time.Time) (*content.Content, error) {
+	delivered, err := s.deliveryService.DeliveriesOn(userID, date)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, delivery := range delivered {
+		if delivery.ContentID == uuid.Nil {
+			continue
+		}
+		c, err := s.contentService.GetContent(delivery.ContentID)
+		if errors.Is(err, content.ErrContentNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &c, nil
+	}
+	return nil, nil
+}
+
+// formatWait renders d rounded up to the minute, e.g. "3h 5m" or "12m".
+func formatWait(d time.Duration) string {
+	minutes := int((d + time.Minute - 1) / time.Minute)
+	if minutes < 60 {
+		return fmt.Sprintf("%dm", max(minutes, 1))
+	}
+	if minutes%60 == 0 {
+		return fmt.Sprintf("%dh", minutes/60)
+	}
+	return fmt.Sprintf("%dh %dm", minutes/60, minutes%60)
+}
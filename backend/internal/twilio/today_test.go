@@ -0,0 +1,138 @@
+package twilio
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"novissima/internal/content"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	start := time.Date(2026, 11, 2, 8, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		reservations  []time.Duration
+		releaseLast   bool
+		at            time.Duration
+		wantAllowed   bool
+		wantRetryWait time.Duration
+	}{
+		{name: "under the limit", reservations: []time.Duration{0}, at: time.Hour, wantAllowed: true},
+		{
+			name:          "at the limit waits for the oldest to expire",
+			reservations:  []time.Duration{0, time.Hour, 2 * time.Hour},
+			at:            3 * time.Hour,
+			wantRetryWait: 21 * time.Hour,
+		},
+		{
+			name:         "expired events free a slot",
+			reservations: []time.Duration{0, time.Hour, 2 * time.Hour},
+			at:           24 * time.Hour,
+			wantAllowed:  true,
+		},
+		{
+			name:         "released slot can be taken again",
+			reservations: []time.Duration{0, time.Hour, 2 * time.Hour},
+			releaseLast:  true,
+			at:           3 * time.Hour,
+			wantAllowed:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := newRateLimiter(3, 24*time.Hour)
+			var release func()
+			for _, offset := range tt.reservations {
+				var wait time.Duration
+				release, wait = limiter.reserve("+15550000001", start.Add(offset))
+				if release == nil {
+					t.Fatalf("reservation at +%s refused, retry after %s", offset, wait)
+				}
+			}
+			if tt.releaseLast {
+				release()
+			}
+
+			release, wait := limiter.reserve("+15550000001", start.Add(tt.at))
+			if allowed := release != nil; allowed != tt.wantAllowed {
+				t.Fatalf("allowed = %v, want %v", allowed, tt.wantAllowed)
+			}
+			if wait != tt.wantRetryWait {
+				t.Errorf("retryAfter = %s, want %s", wait, tt.wantRetryWait)
+			}
+
+			if _, wait := limiter.reserve("+15550000002", start.Add(tt.at)); wait != 0 {
+				t.Errorf("another number was limited for %s", wait)
+			}
+		})
+	}
+}
+
+func TestRateLimiterReserveConcurrent(t *testing.T) {
+	limiter := newRateLimiter(3, 24*time.Hour)
+	now := time.Date(2026, 11, 2, 8, 0, 0, 0, time.UTC)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if release, _ := limiter.reserve("+15550000001", now); release != nil {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed != 3 {
+		t.Errorf("%d concurrent reservations allowed, want 3", allowed)
+	}
+}
+
+func TestSendTodayUsesTheLedger(t *testing.T) {
+	tests := []struct {
+		name      string
+		delivered bool
+		wantReply string
+		wantBody  string
+	}{
+		{name: "sends what the broadcast sent", delivered: true, wantBody: "Remember death."},
+		// Nothing is approved, so there is nothing to select either.
+		{name: "selects when nothing was sent", wantReply: "Sorry, there is no text for today yet."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			user := env.addUser(t, "+15550000001", "en")
+			if tt.delivered {
+				c := env.addContent(t, content.ContentCreate{TextEnglish: "Remember death."})
+				date, _ := user.DueDate(time.Now())
+				if err := env.deliveries.RecordSent(user.ID, c.ID, date, "SM1"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := env.service.sendToday(user.PhoneNumber); got != tt.wantReply {
+				t.Errorf("reply = %q, want %q", got, tt.wantReply)
+			}
+			sent := env.recorder.MessagesTo(user.PhoneNumber)
+			if tt.wantBody == "" {
+				if len(sent) != 0 {
+					t.Errorf("sent %d messages, want none", len(sent))
+				}
+				return
+			}
+			if len(sent) != 1 || sent[0].Body != tt.wantBody {
+				t.Errorf("sent %+v, want %q", sent, tt.wantBody)
+			}
+		})
+	}
+}
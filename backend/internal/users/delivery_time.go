@@ -0,0 +1,49 @@
+package users
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	DefaultTimezone      = "UTC"
+	DefaultPreferredHour = 8
+)
+
+// Location returns the user's time zone, falling back to UTC when the stored
+// name is empty or unknown.
+func (u User) Location() *time.Location {
+	if u.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		log.Printf("Unknown timezone %q for user %s, using UTC", u.Timezone, u.PhoneNumber)
+		return time.UTC
+	}
+	return loc
+}
+
+// DueDate reports whether the user's delivery time has arrived at now, and
+// the local calendar date the delivery belongs to.
+func (u User) DueDate(now time.Time) (time.Time, bool) {
+	local := now.In(u.Location())
+	date := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+	return date, local.Hour() >= u.PreferredHour
+}
+
+func (s *Service) UpdateUserDeliveryTime(phoneNumber string, timezone string, hour int) error {
+	if _, err := time.LoadLocation(timezone); err != nil || timezone == "" || timezone == "Local" {
+		return fmt.Errorf("unknown timezone: %s", timezone)
+	}
+	if hour < 0 || hour > 23 {
+		return fmt.Errorf("hour must be between 0 and 23, got %d", hour)
+	}
+
+	return s.store.UpdateUser(phoneNumber, UserUpdate{
+		Timezone:      &timezone,
+		PreferredHour: &hour,
+		UpdatedAt:     time.Now(),
+	})
+}
@@ -1,54 +1,60 @@
 package users
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
 	"novissima/internal/logging"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/supabase-community/supabase-go"
 )
 
 type Service struct {
-	client *supabase.Client
+	store UserStore
 	loggingService *logging.Service
 }
 
 type UserCreate struct {
-	PhoneNumber string `json:"phone_number"`
-	Active      bool   `json:"active"`
-	Language    string `json:"language"`
+	PhoneNumber   string `json:"phone_number"`
+	Active        bool   `json:"active"`
+	Language      string `json:"language"`
+	Timezone      string `json:"timezone"`
+	PreferredHour int    `json:"preferred_hour"`
 }
 
 type UserUpdate struct {
-	Active    *bool      `json:"active,omitempty"`
-	Language  *string    `json:"language,omitempty"`
-	UpdatedAt time.Time  `json:"updated_at"`
+	Active        *bool      `json:"active,omitempty"`
+	Language      *string    `json:"language,omitempty"`
+	Timezone      *string    `json:"timezone,omitempty"`
+	PreferredHour *int       `json:"preferred_hour,omitempty"`
+	UpdatedAt     time.Time  `json:"updated_at"`
 }
 
 type User struct {
-	ID          uuid.UUID `json:"id"`
-	PhoneNumber string    `json:"phone_number"`
-	Active      bool      `json:"active"`
-	Language    string    `json:"language"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID            uuid.UUID `json:"id"`
+	PhoneNumber   string    `json:"phone_number"`
+	Active        bool      `json:"active"`
+	Language      string    `json:"language"`
+	Timezone      string    `json:"timezone"`
+	PreferredHour int       `json:"preferred_hour"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
-func NewService(client *supabase.Client, loggingService *logging.Service) *Service {
+func NewService(store UserStore, loggingService *logging.Service) *Service {
 	return &Service{
-		client: client,
+		store: store,
 		loggingService: loggingService,
 	}
 }
 
 func (s *Service) AddUser(phoneNumber string) (User, error) {
 	user := UserCreate{
-		PhoneNumber:  phoneNumber,
-		Active:       true,
-		Language:     "en",
+		PhoneNumber:   phoneNumber,
+		Active:        true,
+		Language:      "en",
+		Timezone:      DefaultTimezone,
+		PreferredHour: DefaultPreferredHour,
 	}
 
 	existingUser, err := s.GetUserByPhoneNumber(phoneNumber)
@@ -56,83 +62,73 @@ func (s *Service) AddUser(phoneNumber string) (User, error) {
 		return existingUser, nil
 	}
 	
-	data, _, err := s.client.From("users").Insert(user, true, "", "", "").Execute()
+	createdUser, err := s.store.CreateUser(user)
 	if err != nil {
-		log.Printf("Supabase error: %v", err)
+		log.Printf("Store error: %v", err)
 		return User{}, fmt.Errorf("failed to add user: %w", err)
 	}
 
-	var createdUsers []User
-	if err := json.Unmarshal(data, &createdUsers); err != nil {
-		return User{}, fmt.Errorf("failed to parse created user: %w", err)
-	}
-	
-	if len(createdUsers) == 0 {
-		return User{}, fmt.Errorf("no user was created")
-	}
-	
-	createdUser := createdUsers[0]
 	s.loggingService.LogUserCreated(createdUser.ID, createdUser.PhoneNumber)
 	log.Printf("Successfully added user with phone: %s", phoneNumber)
 	return createdUser, nil
 }
 
 func (s *Service) GetAllActiveUsers() ([]User, error) {
-	var users []User
-	
-	data, _, err := s.client.From("users").
-		Select("*", "", false).
-		Eq("active", "true").
-		Execute()
+	users, err := s.store.GetActiveUsers()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get active users: %w", err)
 	}
-
-	if err := json.Unmarshal(data, &users); err != nil {
-		return nil, fmt.Errorf("failed to parse users data: %w", err)
-	}
 				
-	log.Printf("Found %d active users:", len(users))
-	for _, user := range users {
-		log.Printf("  - Phone: %s, Created: %s", user.PhoneNumber, user.CreatedAt.Format(time.RFC3339))
-	}
+	log.Printf("Found %d active users", len(users))
 	
 	return users, nil
 }
-func (s *Service) GetUserByPhoneNumber(phoneNumber string) (User, error) {
-	var users []User
-	
-	data, _, err := s.client.From("users").
-		Select("*", "", false).
-		Eq("phone_number", phoneNumber).
-		Execute()
+
+// ListUsers returns one page of users matching filter along with the total
+// number of matches.
+func (s *Service) ListUsers(filter UserFilter) ([]User, int, error) {
+	users, err := s.store.ListUsers(filter)
 	if err != nil {
-		return User{}, fmt.Errorf("failed to get user by phone number: %w", err)
+		return nil, 0, fmt.Errorf("failed to list users: %w", err)
 	}
 
-	if err := json.Unmarshal(data, &users); err != nil {
-		return User{}, fmt.Errorf("failed to parse user data: %w", err)
+	total, err := s.store.CountUsers(filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
 	}
 
-	if len(users) == 0 {
-		return User{}, fmt.Errorf("user not found")
+	return users, total, nil
+}
+
+func (s *Service) GetUserByPhoneNumber(phoneNumber string) (User, error) {
+	user, err := s.store.GetUserByPhoneNumber(phoneNumber)
+	if err != nil {
+		return User{}, fmt.Errorf("failed to get user by phone number: %w", err)
 	}
 
-	return users[0], nil
+	return user, nil
 }
 
 func (s *Service) UpdateUserStatus(phoneNumber string, status bool) error {
-	_, _, err := s.client.From("users").
-		Update(map[string]interface{}{"active": status}, "", "").
-		Eq("phone_number", phoneNumber).
-		Execute()
-	return err
+	return s.store.UpdateUser(phoneNumber, UserUpdate{
+		Active:    &status,
+		UpdatedAt: time.Now(),
+	})
 }		
 
+// IsValidLanguage reports whether language is one users can choose: English,
+// Latin or both.
+func IsValidLanguage(language string) bool {
+	switch language {
+	case "en", "la", "both":
+		return true
+	}
+	return false
+}
+
 func (s *Service) UpdateUserLanguage(phoneNumber string, language string) error {
-	_, _, err := s.client.From("users").
-		Update(map[string]interface{}{"language": language}, "", "").
-		Eq("phone_number", phoneNumber).
-		Execute()
-	return err
+	return s.store.UpdateUser(phoneNumber, UserUpdate{
+		Language:  &language,
+		UpdatedAt: time.Now(),
+	})
 }		
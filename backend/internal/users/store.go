@@ -0,0 +1,39 @@
+package users
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrUserNotFound = errors.New("user not found")
+
+// UserFilter selects users. Zero values mean "any"; CreatedFrom is
+// inclusive and CreatedBefore exclusive.
+type UserFilter struct {
+	Search        string
+	Active        *bool
+	Language      string
+	CreatedFrom   time.Time
+	CreatedBefore time.Time
+	Limit         int
+	Offset        int
+}
+
+type UserStore interface {
+	CreateUser(user UserCreate) (User, error)
+	GetActiveUsers() ([]User, error)
+	// ListUsers returns the users matching filter, newest first.
+	ListUsers(filter UserFilter) ([]User, error)
+	// CountUsers counts the users matching filter, ignoring Limit and Offset.
+	CountUsers(filter UserFilter) (int, error)
+	GetUserByPhoneNumber(phoneNumber string) (User, error)
+	UpdateUser(phoneNumber string, update UserUpdate) error
+	// FollowTheme does nothing if the user already follows theme.
+	FollowTheme(userID uuid.UUID, theme string) error
+	// UnfollowTheme returns ErrNotFollowing if the user does not follow theme.
+	UnfollowTheme(userID uuid.UUID, theme string) error
+	// ListThemeFollows returns the follows of userIDs.
+	ListThemeFollows(userIDs []uuid.UUID) ([]ThemeFollow, error)
+}
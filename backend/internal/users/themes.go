@@ -0,0 +1,64 @@
+package users
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrNotFollowing = errors.New("user does not follow theme")
+
+// ThemeFollow is a user's subscription to one content theme. Users who
+// follow no theme get the day's rotation like everyone else.
+type ThemeFollow struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Theme     string    `json:"theme"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (s *Service) FollowTheme(userID uuid.UUID, theme string) error {
+	if err := s.store.FollowTheme(userID, theme); err != nil {
+		return fmt.Errorf("failed to follow theme: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) UnfollowTheme(userID uuid.UUID, theme string) error {
+	if err := s.store.UnfollowTheme(userID, theme); err != nil {
+		return fmt.Errorf("failed to unfollow theme: %w", err)
+	}
+	return nil
+}
+
+// FollowedThemes returns the themes userID follows, sorted.
+func (s *Service) FollowedThemes(userID uuid.UUID) ([]string, error) {
+	byUser, err := s.FollowedThemesByUser([]uuid.UUID{userID})
+	if err != nil {
+		return nil, err
+	}
+	return byUser[userID], nil
+}
+
+// FollowedThemesByUser returns the sorted themes each of userIDs follows.
+// Users who follow nothing have no entry.
+func (s *Service) FollowedThemesByUser(userIDs []uuid.UUID) (map[uuid.UUID][]string, error) {
+	byUser := map[uuid.UUID][]string{}
+	if len(userIDs) == 0 {
+		return byUser, nil
+	}
+
+	follows, err := s.store.ListThemeFollows(userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list followed themes: %w", err)
+	}
+	for _, follow := range follows {
+		byUser[follow.UserID] = append(byUser[follow.UserID], follow.Theme)
+	}
+	for _, themes := range byUser {
+		sort.Strings(themes)
+	}
+	return byUser, nil
+}
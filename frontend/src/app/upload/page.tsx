@@ -32,6 +32,7 @@ export default function UploadPage() {
     image: null as File | null,
   });
   const apiUrl = process.env.NEXT_PUBLIC_API_URL;
+  const apiToken = process.env.NEXT_PUBLIC_API_TOKEN;
 
   const handleInputChange = (field: string, value: string) => {
     setFormData((prev) => ({ ...prev, [field]: value }));
@@ -62,6 +63,7 @@ export default function UploadPage() {
 
       const response = await fetch(`${apiUrl}/content`, {
         method: "POST",
+        headers: apiToken ? { Authorization: `Bearer ${apiToken}` } : undefined,
         body: formDataToSend,
       });
 
@@ -171,10 +173,10 @@ export default function UploadPage() {
                 <Input
                   id="image"
                   type="file"
-                  accept="image/*"
+                  accept="image/jpeg,image/png"
                   onChange={handleFileChange}
                 />
-                <p>Maximum file size: 5MB. Supported formats: JPEG, PNG, GIF</p>
+                <p>Maximum file size: 20MB. Supported formats: JPEG, PNG. Large images are resized for WhatsApp.</p>
               </div>
 
               <Button type="submit" disabled={isLoading} className="w-full">